@@ -0,0 +1,67 @@
+// transcribe-diff is a tool for comparing two transcripts of the same audio,
+// such as from different providers or model settings. It writes an HTML report
+// with color-coded differences, which makes accuracy reviews tractable.
+//
+// Deprecated: use 'transcribe eval', which it is equivalent to.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/herohde/transcribe/pkg/diff"
+	"github.com/herohde/transcribe/pkg/util/filex"
+	"github.com/seekerror/logw"
+)
+
+var (
+	output = flag.String("out", "", "Output HTML file. If not provided, the report is written to stdout.")
+	title  = flag.String("title", "", "Title of the report. Defaults to the name of the first transcript.")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, `usage: transcribe-diff [options] a.txt b.txt
+
+Transcribe-diff compares two transcripts of the same audio word by word and
+writes an HTML report with color-coded substitutions, deletions and insertions.
+It is equivalent to 'transcribe eval', which should be used instead.
+Options:
+`)
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	if len(flag.Args()) != 2 {
+		flag.Usage()
+		logw.Exitf(ctx, "Two transcripts must be provided.")
+	}
+
+	r, err := diff.Compare(flag.Arg(0), flag.Arg(1), *title)
+	if err != nil {
+		logw.Exitf(ctx, "Failed to read transcripts: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteHTML(&buf, r); err != nil {
+		logw.Exitf(ctx, "Failed to write report: %v", err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = filex.WriteFile(*output, buf.Bytes(), 0644, true)
+	}
+	if err != nil {
+		logw.Exitf(ctx, "Failed to write report: %v", err)
+	}
+
+	s := diff.Summarize(r.Edits)
+	logw.Infof(ctx, "Compared %v words: %v substitutions, %v deletions, %v insertions (%.1f%%)", s.Words, s.Substitutions, s.Deletions, s.Insertions, s.Rate()*100)
+}
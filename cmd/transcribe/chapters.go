@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+// chapters returns the chapters of the audio file as empty sections: the cue
+// points of wav files or, for other formats, the chapter markers reported by
+// ffprobe, if installed. It returns nil if the file has no chapters.
+func chapters(ctx context.Context, filename string) ([]transcribe.Section, error) {
+	if isWAV(filename) {
+		cues, err := wav.ReadCues(filename)
+		if err != nil {
+			return nil, err
+		}
+		var ret []transcribe.Section
+		for _, c := range cues {
+			ret = append(ret, transcribe.Section{Title: c.Label, Start: c.Offset})
+		}
+		return ret, nil
+	}
+
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return nil, nil
+	}
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_chapters", "-of", "json", pathx.External(filename)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var result struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %v", err)
+	}
+
+	var ret []transcribe.Section
+	for _, c := range result.Chapters {
+		sec, err := strconv.ParseFloat(c.StartTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chapter start %q: %v", c.StartTime, err)
+		}
+		ret = append(ret, transcribe.Section{Title: c.Tags["title"], Start: time.Duration(sec * float64(time.Second))})
+	}
+	return ret, nil
+}
+
+// writeChapters writes a transcript per chapter of the source file, if any, such
+// as 'foo.wav.01.txt', with a "[hh:mm:ss] title" heading. The phrases of each
+// chapter are formatted with the given function.
+func writeChapters(ctx context.Context, source, output string, phrases []transcribe.Phrase, render func([]transcribe.Phrase) string, opts options) error {
+	list, err := chapters(ctx, source)
+	if err != nil {
+		return err
+	}
+	for i, c := range transcribe.SplitAt(phrases, list) {
+		title := c.Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %v", i+1)
+		}
+		data := fmt.Sprintf("[%v] %v\n\n%v", transcribe.FormatTimestamp(c.Start), title, strings.TrimSpace(render(c.Phrases)))
+
+		filename := fmt.Sprintf("%v.%02d.txt", strings.TrimSuffix(output, filepath.Ext(output)), i+1)
+		if err := writeFile(filename, encode([]byte(data), opts), opts); err != nil {
+			return err
+		}
+	}
+	if len(list) > 0 {
+		infof(ctx, "Wrote %v chapters of %v", len(list), filepath.Base(source))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/format"
+	"github.com/herohde/transcribe/pkg/util/filex"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+	"github.com/seekerror/logw"
+)
+
+// bucketRE matches temporary buckets of runs with generated run IDs.
+var bucketRE = regexp.MustCompile(`^transcribe-(\d{8}-\d{6})-[0-9a-f]{4}$`)
+
+// cleanupMain removes temporary GCS buckets and local lock and temporary files
+// left behind by interrupted runs.
+func cleanupMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("cleanup", "cleanup [options]", `Cleanup removes temporary GCS buckets and objects, as well as lock and
+temporary output files, left behind by interrupted runs. Buckets are only
+removed if the project is provided.`)
+	project := fs.String("project", "", "GCP project of the temporary buckets. If not provided, no buckets are removed.")
+	runID := fs.String("run-id", "", "Run ID of the temporary bucket to remove. If not provided, buckets of runs with generated IDs older than --min-age are removed.")
+	minAge := fs.Duration("min-age", 48*time.Hour, "Minimum age of runs to remove buckets of and of lock and temporary files to remove, so that those of runs in progress are kept.")
+	out := fs.String("out", "", "Directory of output files to remove abandoned lock and temporary files of outputs from, recursively. If not provided, no files are removed.")
+	dryrun := fs.Bool("dry-run", false, "Only report what would be removed.")
+	fs.Parse(args)
+
+	if *project == "" && *out == "" {
+		fs.Usage()
+		return exitErrorf(exitConfig, "No project or output directory provided.")
+	}
+
+	if *project != "" {
+		cl, err := storagex.NewClient(ctx)
+		if err != nil {
+			return exitErrorf(exitSetup, "Failed to create GCS client: %v", err)
+		}
+		buckets, err := storagex.ListBuckets(ctx, cl, *project, "transcribe-")
+		if err != nil {
+			return exitErrorf(exitSetup, "Failed to list buckets of project %v: %v", *project, err)
+		}
+		for _, bucket := range buckets {
+			if !isAbandoned(bucket, *runID, *minAge) {
+				continue
+			}
+			if *dryrun {
+				infof(ctx, "Would remove bucket %v", bucket)
+				continue
+			}
+			if err := storagex.DeleteBucket(ctx, cl, bucket); err != nil {
+				logw.Errorf(ctx, "Failed to remove bucket %v: %v", bucket, err)
+				continue
+			}
+			infof(ctx, "Removed bucket %v", bucket)
+		}
+	}
+
+	if *out == "" {
+		return nil
+	}
+	err := filepath.Walk(*out, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if target, ok := filex.Abandoned(path, info, *minAge); !ok || !isOutput(target) {
+			return nil
+		}
+		if *dryrun {
+			infof(ctx, "Would remove %v", path)
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			logw.Errorf(ctx, "Failed to remove %v: %v", path, err)
+			return nil
+		}
+		infof(ctx, "Removed %v", path)
+		return nil
+	})
+	if err != nil {
+		return exitErrorf(exitFailed, "Failed to clean up %v: %v", *out, err)
+	}
+	return nil
+}
+
+// isOutput returns true iff the file has the extension of an output file of
+// run, such as a transcript or response, so that lock and temporary files of
+// other tools are kept.
+func isOutput(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, name := range format.Names() {
+		if f, err := format.Lookup(name); err == nil && f.Ext() == ext {
+			return true
+		}
+	}
+	return ext == ".json" || ext == ".edl"
+}
+
+// isAbandoned returns true iff the bucket is the temporary bucket of the given
+// run or, if no run is given, of a run with a generated ID older than the given
+// age. Buckets with custom run IDs are only removed if given explicitly.
+func isAbandoned(bucket, run string, age time.Duration) bool {
+	if run != "" {
+		return bucket == "transcribe-"+run
+	}
+	m := bucketRE.FindStringSubmatch(bucket)
+	if m == nil {
+		return false
+	}
+	t, err := time.ParseInLocation("20060102-150405", m[1], time.Local)
+	return err == nil && time.Since(t) > age
+}
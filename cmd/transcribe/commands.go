@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// command is a subcommand of the tool, such as "eval". Each command parses its
+// own flags from the remaining arguments.
+type command struct {
+	name    string
+	summary string
+	main    func(ctx context.Context, args []string) error
+}
+
+// commands are the subcommands. Without a command, "run" is assumed.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "Transcribe audio files (default).", runMain},
+		{"status", "Report which audio files have been transcribed.", statusMain},
+		{"eval", "Compare two transcripts of the same audio.", evalMain},
+		{"doctor", "Check the installation and credentials.", doctorMain},
+		{"cleanup", "Remove abandoned temporary buckets and files.", cleanupMain},
+		{"convert", "Convert an audio file as done before upload.", convertMain},
+		{"cost", "Estimate the cost of transcribing audio files.", costMain},
+		{"watch", "Transcribe new audio files in a directory as they appear.", watchMain},
+	}
+}
+
+// lookupCommand returns the command with the given name, if any.
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func printCommands(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %v\t%v\n", c.name, c.summary)
+	}
+	tw.Flush()
+}
+
+// newFlagSet returns a flag set for the given command with the given usage,
+// such as "eval [options] a.txt b.txt", and description.
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: transcribe %v\n\n%v\nOptions:\n", usage, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
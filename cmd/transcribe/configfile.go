@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the config file used, if present and no config file is
+// given explicitly.
+const defaultConfigFile = "transcribe.yaml"
+
+// applyConfigFile sets the flags not given on the command line from the YAML
+// config file, such as:
+//
+//	project: myproject
+//	language: de-DE
+//	max-operations: 10
+//	hints: [Kubernetes, gRPC]
+//
+// Keys are flag names. Lists are joined with commas, except for repeatable
+// flags, such as notify, which are set once per element. If not required, a
+// missing file is ignored.
+func applyConfigFile(fs *flag.FlagSet, filename string, required bool) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("invalid config file %v: %v", filename, err)
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == "config" || fs.Lookup(k) == nil {
+			return fmt.Errorf("invalid config file %v: unknown option %v", filename, k)
+		}
+		if set[k] {
+			continue // command line takes precedence
+		}
+
+		if list, ok := m[k].([]interface{}); ok {
+			if _, ok := fs.Lookup(k).Value.(*multiFlag); ok {
+				for _, e := range list {
+					value, err := configValue(e)
+					if err != nil {
+						return fmt.Errorf("invalid config file %v: option %v: %v", filename, k, err)
+					}
+					if err := fs.Set(k, value); err != nil {
+						return fmt.Errorf("invalid config file %v: option %v: %v", filename, k, err)
+					}
+				}
+				continue
+			}
+		}
+
+		value, err := configValue(m[k])
+		if err != nil {
+			return fmt.Errorf("invalid config file %v: option %v: %v", filename, k, err)
+		}
+		if err := fs.Set(k, value); err != nil {
+			return fmt.Errorf("invalid config file %v: option %v: %v", filename, k, err)
+		}
+	}
+	return nil
+}
+
+// configValue returns the flag value of a YAML value.
+func configValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		var list []string
+		for _, e := range v {
+			s, err := configValue(e)
+			if err != nil {
+				return "", err
+			}
+			list = append(list, s)
+		}
+		return strings.Join(list, ","), nil
+	case map[string]interface{}:
+		return "", fmt.Errorf("nested options not supported")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		args     []string
+		expected map[string]string // values of set flags, nil if error
+	}{
+		{
+			"plain",
+			"project: myproject\nlanguage: de-DE\nmax-operations: 10\ndry-run: true\n",
+			nil,
+			map[string]string{"project": "myproject", "language": "de-DE", "max-operations": "10", "dry-run": "true"},
+		},
+		{
+			"lists",
+			"hints: [Kubernetes, gRPC]\nnotify:\n  - slack:a,b\n  - pubsub:c\n",
+			nil,
+			map[string]string{"hints": "Kubernetes,gRPC", "notify": "slack:a,b pubsub:c"},
+		},
+		{
+			"precedence",
+			"project: myproject\nlanguage: de-DE\n",
+			[]string{"--project=other"},
+			map[string]string{"project": "other", "language": "de-DE"},
+		},
+		{
+			"empty",
+			"",
+			nil,
+			map[string]string{},
+		},
+		{"unknown", "bogus: 1\n", nil, nil},
+		{"config", "config: other.yaml\n", nil, nil},
+		{"nested", "project:\n  id: myproject\n", nil, nil},
+		{"invalid value", "max-operations: many\n", nil, nil},
+		{"invalid yaml", "project: [myproject\n", nil, nil},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		filename := filepath.Join(dir, "transcribe.yaml")
+		if err := os.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fs := newTestFlagSet()
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		err := applyConfigFile(fs, filename, true)
+		if tt.expected == nil {
+			if err == nil {
+				t.Errorf("applyConfigFile(%v) succeeded, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("applyConfigFile(%v) failed: %v", tt.name, err)
+			continue
+		}
+
+		actual := map[string]string{}
+		fs.Visit(func(f *flag.Flag) {
+			actual[f.Name] = f.Value.String()
+		})
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("applyConfigFile(%v) = %v, want %v", tt.name, actual, tt.expected)
+		}
+	}
+}
+
+func TestApplyConfigFileMissing(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "transcribe.yaml")
+
+	if err := applyConfigFile(newTestFlagSet(), filename, false); err != nil {
+		t.Errorf("applyConfigFile(optional) failed: %v", err)
+	}
+	if err := applyConfigFile(newTestFlagSet(), filename, true); err == nil {
+		t.Errorf("applyConfigFile(required) succeeded, want error")
+	}
+}
+
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.String("project", "", "")
+	fs.String("language", "", "")
+	fs.Int("max-operations", 0, "")
+	fs.Bool("dry-run", false, "")
+	fs.String("hints", "", "")
+	fs.Var(&multiFlag{}, "notify", "")
+	return fs
+}
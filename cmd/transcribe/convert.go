@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/herohde/transcribe/pkg/audio"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/cache"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+// transcode converts the given file with the converter into a temporary file.
+// If a cache is provided, the converted file is reused across runs. It returns
+// the converted file and a cleanup function.
+func transcode(ctx context.Context, conv audio.Converter, filename string, opts audio.Options, c *cache.Cache) (string, func(), error) {
+	settings := []string{conv.Name(), fmt.Sprint(opts.Mono), fmt.Sprint(opts.Normalize), fmt.Sprint(opts.SampleRate), fmt.Sprint(opts.Channels), fmt.Sprint(opts.Track)}
+	return convert(ctx, filename, conv.Ext(), settings, c, func(tmp string) error {
+		if err := conv.Convert(ctx, filename, tmp, opts); err != nil {
+			return fmt.Errorf("failed to convert %v: %v", filepath.Base(filename), err)
+		}
+		return nil
+	})
+}
+
+// compress losslessly compresses the given wav file to a 16-bit flac file to
+// reduce upload size. It uses ffmpeg or, if not installed, sox.
+func compress(ctx context.Context, filename string, c *cache.Cache) (string, func(), error) {
+	if conv, err := audio.Lookup("ffmpeg"); err == nil && conv.Probe(filename, audio.Options{}) == nil {
+		return transcode(ctx, conv, filename, audio.Options{}, c)
+	}
+
+	return convert(ctx, filename, ".flac", []string{"sox", "-b 16", "flac"}, c, func(tmp string) error {
+		out, err := exec.CommandContext(ctx, "sox", pathx.External(filename), "-b", "16", pathx.External(tmp)).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to compress %v (err=%v): %v. Do you have sox or ffmpeg installed?", filepath.Base(filename), err, string(out))
+		}
+		return nil
+	})
+}
+
+// wrap adds a wav header with the given format to the given headerless PCM file.
+// It returns the wav file and a cleanup function.
+func wrap(ctx context.Context, filename string, h *wav.Header, c *cache.Cache) (string, func(), error) {
+	settings := []string{"pcm", fmt.Sprint(h.Format), fmt.Sprint(h.Channels), fmt.Sprint(h.SampleRate), fmt.Sprint(h.BitsPerSample)}
+	return convert(ctx, filename, ".wav", settings, c, func(tmp string) error {
+		if err := wav.Wrap(filename, tmp, h); err != nil {
+			return fmt.Errorf("failed to read %v as PCM: %v", filepath.Base(filename), err)
+		}
+		return nil
+	})
+}
+
+// trim removes silences from the given wav file in-process. It returns the
+// trimmed file, the kept segments of the original audio and a cleanup function.
+func trim(ctx context.Context, filename string, opts wav.SilenceOptions, c *cache.Cache) (string, []wav.Segment, func(), error) {
+	segments, err := wav.Voiced(filename, opts)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to detect silence in %v: %v", filepath.Base(filename), err)
+	}
+
+	settings := []string{"trim", fmt.Sprint(opts.Threshold), opts.MinDuration.String(), opts.Padding.String()}
+	trimmed, cleanup, err := convert(ctx, filename, ".wav", settings, c, func(tmp string) error {
+		if err := wav.Cut(filename, tmp, segments); err != nil {
+			return fmt.Errorf("failed to trim %v: %v", filepath.Base(filename), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return trimmed, segments, cleanup, nil
+}
+
+// split cuts the given wav file into the given segments in-process. It returns
+// the files and a cleanup function.
+func split(ctx context.Context, filename string, chunks []wav.Segment) ([]string, func(), error) {
+	var files []string
+	cleanup := func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}
+
+	for _, c := range chunks {
+		part, _, err := convert(ctx, filename, ".wav", nil, nil, func(tmp string) error {
+			if err := wav.Cut(filename, tmp, []wav.Segment{c}); err != nil {
+				return fmt.Errorf("failed to split %v at %v: %v", filepath.Base(filename), c.Start, err)
+			}
+			return nil
+		})
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		files = append(files, part)
+	}
+	return files, cleanup, nil
+}
+
+// convert converts the given file into a temporary file with the given
+// extension using fn. If a cache is provided, the converted file is cached by
+// content and settings and reused across runs.
+func convert(ctx context.Context, filename, ext string, settings []string, c *cache.Cache, fn func(tmp string) error) (string, func(), error) {
+	name := filepath.Base(filename)
+
+	var key string
+	if c != nil {
+		k, err := cache.Key(filename, settings...)
+		if err != nil {
+			return "", nil, err
+		}
+		if path, ok := c.Lookup(k, ext); ok {
+			infof(ctx, "Using cached conversion of %v", name)
+			return path, func() {}, nil
+		}
+		key = k
+	}
+
+	fd, err := ioutil.TempFile("", "transcribe-*"+ext)
+	if err != nil {
+		return "", nil, err
+	}
+	fd.Close()
+	tmp := fd.Name()
+
+	if err := fn(tmp); err != nil {
+		os.Remove(tmp)
+		return "", nil, err
+	}
+
+	if c == nil {
+		return tmp, func() { os.Remove(tmp) }, nil
+	}
+	path, err := c.Store(key, ext, tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", nil, err
+	}
+	return path, func() {}, nil
+}
+
+// convertMain converts an audio file with the options of run, such as to check
+// the audio that would be uploaded.
+func convertMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("convert", "convert [options] in out", `Convert converts an audio file to 16-bit wav or flac, as done before upload,
+such as to listen to the audio that would be transcribed. The output file
+extension must match the converter.`)
+	mono := fs.Bool("mono", false, "Downmix to mono.")
+	loudnorm := fs.Bool("normalize", false, "Normalize the loudness.")
+	resample := fs.Int("sample-rate", 0, "Resample to the given rate in Hz, such as 16000. If zero, the sample rate is unchanged.")
+	chans := fs.String("channels", "", "Comma-separated list of channels to keep, 1-based. Selected channels are mixed to mono. If not provided, all channels are kept.")
+	track := fs.Int("track", 0, "Audio track to convert, 1-based. Requires ffmpeg. If zero, the default track is used.")
+	conv := fs.String("converter", "auto", "Converter: native (wav only), sox, ffmpeg or auto to use the first that can convert the file.")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "An input and output file must be provided.")
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+
+	opts := audio.Options{Mono: *mono, Normalize: *loudnorm, SampleRate: *resample, Track: *track}
+	if *chans != "" {
+		list, err := parseChannels(*chans)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid channels: %v", err)
+		}
+		opts.Channels = list
+	}
+
+	var c audio.Converter
+	var err error
+	if *conv == "auto" {
+		c, err = audio.Find(in, opts)
+	} else if c, err = audio.Lookup(*conv); err == nil {
+		err = c.Probe(in, opts)
+	}
+	if err != nil {
+		return exitErrorf(exitConfig, "Cannot convert %v: %v", in, err)
+	}
+	if ext := filepath.Ext(out); ext != c.Ext() {
+		return exitErrorf(exitConfig, "Invalid output file %v: converter %v writes %v files", out, c.Name(), c.Ext())
+	}
+
+	if err := c.Convert(ctx, in, out, opts); err != nil {
+		return exitErrorf(exitFailed, "Failed to convert %v: %v", in, err)
+	}
+	infof(ctx, "Converted %v to %v using %v", in, out, c.Name())
+	return nil
+}
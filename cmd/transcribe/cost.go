@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// costMain prints an itemized estimate of the cost of transcribing the audio
+// files at list prices.
+func costMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("cost", "cost [options] file [...]", `Cost probes the duration of audio files and prints an itemized estimate of the
+cost of transcribing them at the list prices of the model, such as to budget
+large archive jobs. The audio of each file is billed in 15s increments.
+Durations of headerless PCM files are computed from the size and the
+--pcm-* format. Durations of other formats than wav and flac are probed with
+ffprobe. Files of a --manifest are priced with their models. With
+--separate-channels, each channel of wav and flac files is billed.`)
+	config := fs.String("config-json", "", "File with a Speech API v1 RecognitionConfig in JSON format, as given to run, for the model.")
+	model := fs.String("model", "", "Recognition model, as given to run. The 'video' model is enhanced.")
+	enhance := fs.Bool("enhanced", false, "Use enhanced model prices, such as for enhanced phone_call models.")
+	logging := fs.Bool("data-logging", false, "Use the discounted prices of projects opted in to data logging.")
+	manifest := fs.String("manifest", "", "CSV or JSON manifest of files with per-file options, as given to run, for the models of the files.")
+	separate := fs.Bool("separate-channels", false, "Bill each channel of stereo or multichannel wav and flac files, as if given to run.")
+	include := fs.String("include", "", "Comma-separated list of patterns of files to include when searching directories or expanding glob patterns, as given to run.")
+	exclude := fs.String("exclude", "", "Comma-separated list of patterns of files to skip when searching directories or expanding glob patterns, as given to run.")
+	pcmrate := fs.Int("pcm-rate", 0, "Sample rate in Hz of headerless .pcm or .raw files, as given to run. Required to probe such files.")
+	pcmchans := fs.Int("pcm-channels", 1, "Number of interleaved channels of headerless .pcm or .raw files, as given to run.")
+	pcmbits := fs.Int("pcm-bits", 16, "Bits per sample of headerless .pcm or .raw files, as given to run.")
+	pcmenc := fs.String("pcm-encoding", "linear", "Encoding of headerless .pcm or .raw files, as given to run: linear, mulaw or alaw.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 && *manifest == "" {
+		fs.Usage()
+		return exitErrorf(exitConfig, "No files provided.")
+	}
+	if fs.NArg() > 0 && *manifest != "" {
+		fs.Usage()
+		return exitErrorf(exitConfig, "Files cannot be provided with a manifest.")
+	}
+
+	opts := options{separate: *separate, config: &speechpb.RecognitionConfig{}, jobs: map[string]job{}}
+	if *config != "" {
+		c, err := transcribe.LoadConfig(*config)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid config: %v", err)
+		}
+		opts.config = c
+	}
+	if *model != "" {
+		opts.config.Model = *model
+	}
+	if *enhance {
+		opts.config.UseEnhanced = true
+	}
+	if *pcmrate > 0 {
+		h, err := pcmFormat(*pcmenc, *pcmrate, *pcmchans, *pcmbits)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid PCM format: %v", err)
+		}
+		opts.pcm = h
+	}
+
+	sel := selection{include: *include, exclude: *exclude}
+
+	var files []string
+	var cleanup func()
+	var err error
+	if *manifest != "" {
+		files, cleanup, err = manifestInputs(ctx, *manifest, sel, nil, opts.jobs)
+	} else {
+		files, cleanup, err = inputs(ctx, fs.Args(), sel, nil)
+	}
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid files: %v", err)
+	}
+	defer cleanup()
+
+	for _, file := range files {
+		if isObject(file) && opts.storage == nil {
+			cl, err := storagex.NewClient(ctx)
+			if err != nil {
+				return exitErrorf(exitSetup, "Failed to create GCS client: %v", err)
+			}
+			opts.storage = cl // object headers are read for the duration
+		}
+	}
+
+	var total, billed time.Duration
+	var cost float64
+	var unknown int
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tDURATION\tBILLED\tCOST")
+	for _, file := range files {
+		d := probe(ctx, file, opts)
+		if d == 0 {
+			fmt.Fprintf(tw, "%v\tunknown\t\t\n", filepath.Base(file))
+			unknown++
+			continue
+		}
+		b := newBillable(ctx, file, d, *logging, opts)
+		fmt.Fprintf(tw, "%v\t%v\t%v\t$%.2f\n", filepath.Base(file), d.Round(time.Second), b.billed(0), b.cost(0))
+
+		total += d
+		billed += b.billed(0)
+		cost += b.cost(0)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%v\t%v\t$%.2f\n", total.Round(time.Second), billed, cost)
+	tw.Flush()
+
+	enhanced := transcribe.IsEnhanced(opts.config)
+	kind, discount := "standard", "without"
+	if enhanced {
+		kind = "enhanced"
+	}
+	if *logging {
+		discount = "with"
+	}
+	fmt.Printf("\nPrice: $%.3f per minute (%v model, %v data logging), %v files\n", transcribe.Price(enhanced, *logging), kind, discount, len(files))
+	if len(opts.jobs) > 0 {
+		fmt.Println("Files with a model in the manifest are billed at the price of that model")
+	}
+	if unknown > 0 {
+		fmt.Printf("Duration of %v files not known and not included. Install ffprobe (part of ffmpeg) to probe them\n", unknown)
+	}
+	return nil
+}
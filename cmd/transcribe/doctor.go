@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// diagnosis is the result of a doctor check.
+type diagnosis struct {
+	Name, Status, Detail string
+	Required             bool
+}
+
+// doctorMain checks that the external tools, credentials and APIs needed by run
+// are available and reports how to fix any that are not.
+func doctorMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("doctor", "doctor [options]", `Doctor checks that the external tools and credentials used by transcribe are
+available, that the Speech and Storage APIs are enabled on the project and that
+the bucket can be used, and suggests fixes for any that are not. Without
+--bucket, a temporary bucket is created and removed.`)
+	project := fs.String("project", "", "GCP project to use, as given to run. If not provided, the project of the credentials is used.")
+	bucket := fs.String("bucket", "", "GCS bucket to check object permissions of, as given to run. If not provided, creating temporary buckets is checked.")
+	fs.Parse(args)
+
+	var checks []diagnosis
+	for _, tool := range []struct{ name, use string }{
+		{"sox", "mp3 conversion, --flac and --normalize"},
+		{"ffmpeg", "m4a/aac and video conversion, --track and --normalize"},
+		{"ffprobe", "duration estimates and chapters of formats other than wav and flac"},
+	} {
+		c := diagnosis{Name: tool.name, Status: "ok"}
+		if path, err := exec.LookPath(tool.name); err == nil {
+			c.Detail = path
+		} else {
+			c.Status, c.Detail = "missing", fmt.Sprintf("Install %v for %v.", tool.name, tool.use)
+		}
+		checks = append(checks, c)
+	}
+
+	creds := diagnosis{Name: "credentials", Status: "ok", Detail: "Application default credentials found.", Required: true}
+	c, err := google.FindDefaultCredentials(ctx, storage.CloudPlatformScope)
+	if err != nil {
+		creds.Status, creds.Detail = "failed", "Run 'gcloud auth application-default login'."
+	}
+	checks = append(checks, creds)
+
+	id := *project
+	proj := diagnosis{Name: "project", Status: "ok", Detail: *project, Required: true}
+	if *project == "" {
+		if err == nil && c.ProjectID != "" {
+			id = c.ProjectID
+			proj.Detail = fmt.Sprintf("%v (from credentials). Use --project to be explicit.", c.ProjectID)
+		} else {
+			proj.Status, proj.Detail = "missing", "Use --project to provide a project with the Speech API enabled."
+		}
+	}
+	checks = append(checks, proj)
+
+	if creds.Status == "ok" && id != "" {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		checks = append(checks, checkSpeech(ctx, id))
+		if cl, err := storagex.NewClient(ctx); err != nil {
+			checks = append(checks, diagnosis{Name: "storage api", Status: "failed", Detail: err.Error(), Required: true})
+		} else {
+			checks = append(checks, checkStorage(ctx, cl, id), checkBucket(ctx, cl, id, *bucket))
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", c.Name, c.Status, c.Detail)
+	}
+	tw.Flush()
+
+	if creds.Status != "ok" {
+		return exitErrorf(exitSetup, "Credentials not found.")
+	}
+	if proj.Status != "ok" {
+		return exitErrorf(exitConfig, "Check %v failed.", proj.Name)
+	}
+	for _, c := range checks {
+		if c.Required && c.Status != "ok" {
+			return exitErrorf(exitSetup, "Check %v failed.", c.Name)
+		}
+	}
+	infof(ctx, "All required checks passed.")
+	return nil
+}
+
+// checkSpeech checks that the Speech API is enabled on the project and usable
+// by polling a non-existent operation, which is free. The project is used as
+// the quota project, so that the request is checked against it.
+func checkSpeech(ctx context.Context, project string) diagnosis {
+	ret := diagnosis{Name: "speech api", Status: "ok", Detail: "Enabled.", Required: true}
+
+	scl, err := speech.NewClient(ctx, option.WithQuotaProject(project))
+	if err != nil {
+		ret.Status, ret.Detail = "failed", err.Error()
+		return ret
+	}
+	defer scl.Close()
+
+	_, err = scl.LongRunningRecognizeOperation("0").Poll(ctx)
+	switch status.Code(err) {
+	case codes.OK, codes.NotFound, codes.InvalidArgument:
+		// ok: the API handled the request
+	case codes.PermissionDenied, codes.Unauthenticated:
+		ret.Status, ret.Detail = remedy(err, "speech.googleapis.com", project, "roles/speech.client")
+	default:
+		ret.Status, ret.Detail = "failed", err.Error()
+	}
+	return ret
+}
+
+// checkStorage checks that the Storage API is enabled and buckets can be listed.
+func checkStorage(ctx context.Context, cl *storage.Service, project string) diagnosis {
+	ret := diagnosis{Name: "storage api", Status: "ok", Detail: "Enabled.", Required: true}
+	if _, err := storagex.ListBuckets(ctx, cl, project, "transcribe-"); err != nil {
+		ret.Status, ret.Detail = remedy(err, "storage.googleapis.com", project, "roles/storage.admin")
+	}
+	return ret
+}
+
+// checkBucket checks that objects can be created in the given bucket or, if
+// none, that temporary buckets can be created and removed.
+func checkBucket(ctx context.Context, cl *storage.Service, project, bucket string) diagnosis {
+	ret := diagnosis{Name: "bucket", Status: "ok", Required: true}
+
+	if bucket != "" {
+		ret.Detail = fmt.Sprintf("Objects can be staged in %v.", bucket)
+
+		missing, err := storagex.MissingPermissions(ctx, cl, bucket, []string{"storage.objects.create", "storage.objects.delete"})
+		switch {
+		case err != nil:
+			ret.Status, ret.Detail = "failed", fmt.Sprintf("%v. Check the bucket name or create it with 'gcloud storage buckets create gs://%v --project=%v'.", err, bucket, project)
+		case len(missing) > 0:
+			ret.Status, ret.Detail = "denied", fmt.Sprintf("Missing %v. Run 'gcloud storage buckets add-iam-policy-binding gs://%v --member=PRINCIPAL --role=roles/storage.objectAdmin' for the principal of the credentials.", strings.Join(missing, ", "), bucket)
+		}
+		return ret
+	}
+
+	ret.Detail = "Temporary buckets can be created and removed."
+
+	run := newRunID()
+	name := "transcribe-" + run
+	if err := storagex.NewBucket(ctx, cl, project, name); err != nil {
+		ret.Status, ret.Detail = remedy(err, "storage.googleapis.com", project, "roles/storage.admin")
+		return ret
+	}
+	if err := storagex.DeleteBucket(ctx, cl, name); err != nil {
+		ret.Status, ret.Detail = "failed", fmt.Sprintf("Failed to remove test bucket %v: %v. Run 'transcribe cleanup --project=%v --run-id=%v'.", name, err, project, run)
+	}
+	return ret
+}
+
+// remedy returns the status and the fix for an API error: enable the service,
+// if disabled, or grant the role, if denied.
+func remedy(err error, service, project, role string) (string, string) {
+	switch {
+	case isDisabled(err):
+		return "disabled", fmt.Sprintf("Run 'gcloud services enable %v --project=%v'.", service, project)
+	case isAuth(err) || status.Code(err) == codes.PermissionDenied || status.Code(err) == codes.Unauthenticated:
+		return "denied", fmt.Sprintf("Run 'gcloud projects add-iam-policy-binding %v --member=PRINCIPAL --role=%v' for the principal of the credentials.", project, role)
+	default:
+		return "failed", err.Error()
+	}
+}
+
+// isDisabled returns true iff the error is due to an API not enabled on the
+// project.
+func isDisabled(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "SERVICE_DISABLED") || strings.Contains(msg, "accessNotConfigured") || strings.Contains(msg, "has not been used in project")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/util/drivex"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+	"google.golang.org/api/drive/v3"
+)
+
+// fetchDrive downloads the new audio files in the given Drive folder to the
+// local directory.
+func fetchDrive(ctx context.Context, cl *drive.Service, folder, dir string, opts options) ([]string, error) {
+	list, err := newDriveFiles(ctx, cl, folder, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, f := range list {
+		filename := filepath.Join(dir, pathx.SafeName(f.Name))
+		if err := drivex.Download(ctx, cl, f.Id, filename); err != nil {
+			return nil, err
+		}
+		ret = append(ret, filename)
+	}
+	return ret, nil
+}
+
+// newDriveFiles returns the new audio files in the given Drive folder. An audio
+// file is new, if the folder has no transcript of it.
+func newDriveFiles(ctx context.Context, cl *drive.Service, folder string, opts options) ([]*drive.File, error) {
+	list, err := drivex.List(ctx, cl, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, f := range list {
+		names[f.Name] = true
+	}
+
+	var ret []*drive.File
+	seen := map[string]bool{}
+	for _, f := range list {
+		name := pathx.SafeName(f.Name)
+		if !isSupported(name) || seen[name] {
+			continue
+		}
+		output, err := outputName(f.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		if names[output] {
+			continue
+		}
+		seen[name] = true
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// estimateDrive logs the estimate of transcribing the given Drive files without
+// downloading them. Durations are read from the media metadata, if present, or
+// the header of wav and flac files, as are the channels billed if recognized
+// separately. It returns the estimated cost in USD.
+func estimateDrive(ctx context.Context, cl *drive.Service, list []*drive.File, opts options) float64 {
+	var items []billable
+	for _, f := range list {
+		var d time.Duration
+		var n int // channels, if known
+		switch {
+		case f.VideoMediaMetadata != nil && f.VideoMediaMetadata.DurationMillis > 0:
+			d = time.Duration(f.VideoMediaMetadata.DurationMillis) * time.Millisecond
+		case isWAV(f.Name) || isFLAC(f.Name):
+			head, err := drivex.ReadHead(ctx, cl, f.Id, headSize)
+			if err == nil {
+				d, n, err = readHead(f.Name, head, f.Size)
+			}
+			if err != nil {
+				infof(ctx, "Failed to read header of %v: %v", f.Name, err)
+			}
+		}
+		if d == 0 {
+			infof(ctx, "Duration of %v not known", f.Name)
+		}
+
+		b := billable{duration: d, channels: 1, price: filePrice(f.Name, false, opts)}
+		if opts.separate {
+			b.channels = max(n, 1)
+		}
+		items = append(items, b)
+	}
+	return forecast(ctx, items, opts)
+}
+
+// publishDrive uploads the transcripts of the given files in the output
+// directory to the Drive folder, optionally as Google Docs. Files that failed
+// to transcribe are ignored. It returns the failed uploads.
+func publishDrive(ctx context.Context, cl *drive.Service, folder string, files []string, doc bool, opts options) []failure {
+	var failures []failure
+	for _, file := range files {
+		name, err := outputName(file, opts)
+		if err != nil {
+			failures = append(failures, newFailure(filepath.Base(file), err))
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(opts.out, name))
+		if err != nil {
+			continue // not transcribed
+		}
+		if err := drivex.UploadText(ctx, cl, folder, name, data, doc); err != nil {
+			errorf(ctx, "Failed to upload transcript %v to Drive: %v", name, err)
+			failures = append(failures, newFailure(filepath.Base(file), err))
+			continue
+		}
+		infof(ctx, "Uploaded transcript %v to Drive", name)
+	}
+	return failures
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/audio/flac"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+	"github.com/seekerror/logw"
+	"google.golang.org/api/storage/v1"
+)
+
+// speedup is the approximate ratio of audio duration to processing time of a
+// long-running operation.
+const speedup = 2
+
+// headSize is the number of leading bytes of remote wav and flac files read to
+// determine their format without downloading them.
+const headSize = 64 << 10
+
+// readHead returns the duration and number of channels of a wav or flac file
+// from its leading bytes. The file size repairs missing wav data sizes.
+func readHead(name string, head []byte, size int64) (time.Duration, int, error) {
+	if isFLAC(name) {
+		h, err := flac.ReadHeader(bytes.NewReader(head))
+		if err != nil {
+			return 0, 0, err
+		}
+		return h.Duration(), h.Channels, nil
+	}
+	h, err := wav.ReadHeader(bytes.NewReader(head))
+	if err != nil {
+		return 0, 0, err
+	}
+	h.Repair(size)
+	return h.Duration(), h.Channels, nil
+}
+
+// readObject returns the duration and number of channels of a wav or flac GCS
+// object from its header, without downloading it.
+func readObject(ctx context.Context, cl *storage.Service, uri string) (time.Duration, int, error) {
+	if cl == nil {
+		return 0, 0, fmt.Errorf("no GCS client")
+	}
+	bucket, object, err := storagex.ParseURI(uri)
+	if err != nil {
+		return 0, 0, err
+	}
+	obj, err := storagex.Stat(ctx, cl, bucket, object)
+	if err != nil {
+		return 0, 0, err
+	}
+	head, err := storagex.ReadHead(ctx, cl, bucket, object, headSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readHead(uri, head, int64(obj.Size))
+}
+
+// probe returns the duration of the audio file. The duration of wav and flac
+// files and GCS objects is read from the header and of PCM files computed from
+// the size. Other formats are probed with ffprobe, if installed. It returns
+// zero if the duration is not known.
+func probe(ctx context.Context, filename string, opts options) time.Duration {
+	if isObject(filename) {
+		d, _, err := readObject(ctx, opts.storage, filename)
+		if err != nil {
+			debugf(ctx, "Failed to read header of %v: %v", filename, err)
+		}
+		return d
+	}
+	if h, ok := readWAV(filename); ok {
+		return h.Duration()
+	}
+	if isPCM(filename) {
+		if opts.pcm == nil {
+			return 0
+		}
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return 0
+		}
+		h := *opts.pcm
+		h.DataSize = fi.Size()
+		return h.Duration()
+	}
+	if isFLAC(filename) {
+		if h, err := flac.ReadFile(filename); err == nil {
+			return h.Duration()
+		}
+		return 0
+	}
+
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", pathx.External(filename)).Output()
+	if err != nil {
+		return 0
+	}
+	sec, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+// billable is the audio of a file to be transcribed.
+type billable struct {
+	duration time.Duration // zero if unknown
+	channels int           // channels billed
+	price    float64       // list price in USD per minute
+}
+
+// newBillable returns the billable audio of the file with the given duration.
+// Each channel is billed, if recognized separately, at the price of the
+// effective model of the file, such as given by its manifest job.
+func newBillable(ctx context.Context, file string, d time.Duration, logging bool, opts options) billable {
+	n := 1
+	if opts.separate {
+		if isObject(file) {
+			_, n, _ = readObject(ctx, opts.storage, file)
+		} else if isPCM(file) && opts.pcm != nil {
+			n = opts.pcm.Channels
+		} else {
+			n = channels(file)
+		}
+	}
+	return billable{duration: d, channels: max(n, 1), price: filePrice(file, logging, opts)}
+}
+
+// filePrice returns the list price in USD per minute of transcribing the file
+// with its effective model, with or without data logging.
+func filePrice(file string, logging bool, opts options) float64 {
+	return transcribe.Price(transcribe.IsEnhanced(withJob(opts, file).config), logging)
+}
+
+// billed returns the billed duration of the audio. Each request, i.e., file or
+// chunk, and channel is billed in increments.
+func (b billable) billed(chunk time.Duration) time.Duration {
+	d := b.duration
+	if chunk > 0 && d > chunk {
+		n := d / chunk
+		return time.Duration(b.channels) * (time.Duration(n)*transcribe.Billed(chunk) + transcribe.Billed(d-n*chunk))
+	}
+	return time.Duration(b.channels) * transcribe.Billed(d)
+}
+
+// cost returns the list price in USD of the billed duration of the audio.
+func (b billable) cost(chunk time.Duration) float64 {
+	return b.billed(chunk).Minutes() * b.price
+}
+
+// estimate logs the total audio duration of the files and the projected cost and
+// time of transcribing them from the probed durations. The time is a rough
+// estimate, assuming the files are transcribed in parallel up to the given
+// limit of operations, if any. It returns the estimated cost in USD.
+func estimate(ctx context.Context, files []string, opts options) float64 {
+	var list []billable
+	for _, file := range files {
+		d := opts.durations[file]
+		if d == 0 {
+			infof(ctx, "Duration of %v not known", filepath.Base(file))
+		}
+		list = append(list, newBillable(ctx, file, d, false, opts))
+	}
+	return forecast(ctx, list, opts)
+}
+
+// forecast logs the total of the audio durations and the projected cost and time
+// of transcribing them. Unknown durations are zero. It returns the estimated
+// cost in USD.
+func forecast(ctx context.Context, list []billable, opts options) float64 {
+	var total, longest time.Duration
+	var cost float64
+	var unknown int
+	for _, b := range list {
+		if b.duration == 0 {
+			unknown++
+		}
+		if opts.chunk > 0 && b.duration > opts.chunk {
+			longest = max(longest, opts.chunk)
+		} else {
+			longest = max(longest, b.duration)
+		}
+		total += b.duration
+		cost += b.cost(opts.chunk)
+	}
+
+	eta := longest / speedup
+	if opts.ops != nil {
+		eta = max(eta, total/speedup/time.Duration(cap(opts.ops)))
+	}
+
+	logw.Infof(ctx, tag("Batch of %v files contains %v of audio (%.1f minutes). Estimated cost: $%.2f. Estimated time: %v"), len(list), total.Round(time.Second), total.Minutes(), cost, eta.Round(time.Minute))
+	if unknown > 0 {
+		infof(ctx, "Duration of %v files not known and not included. Install ffprobe (part of ffmpeg) to probe local files", unknown)
+	}
+	return cost
+}
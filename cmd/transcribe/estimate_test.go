@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+func TestBillable(t *testing.T) {
+	tests := []struct {
+		b        billable
+		chunk    time.Duration
+		billed   time.Duration
+		expected float64
+	}{
+		{billable{duration: 0, channels: 1, price: 0.024}, 0, 0, 0},
+		{billable{duration: time.Second, channels: 1, price: 0.024}, 0, 15 * time.Second, 0.006},
+		{billable{duration: 61 * time.Second, channels: 1, price: 0.024}, 0, 75 * time.Second, 0.03},
+		{billable{duration: 61 * time.Second, channels: 2, price: 0.024}, 0, 150 * time.Second, 0.06},
+		{billable{duration: time.Minute, channels: 1, price: 0.036}, 0, time.Minute, 0.036},
+		{billable{duration: 50 * time.Second, channels: 1, price: 0.024}, 20 * time.Second, 75 * time.Second, 0.03},
+		{billable{duration: 50 * time.Second, channels: 2, price: 0.024}, 20 * time.Second, 150 * time.Second, 0.06},
+		{billable{duration: 10 * time.Second, channels: 1, price: 0.024}, 20 * time.Second, 15 * time.Second, 0.006},
+	}
+
+	for _, tt := range tests {
+		if actual := tt.b.billed(tt.chunk); actual != tt.billed {
+			t.Errorf("billed(%+v, %v) = %v, want %v", tt.b, tt.chunk, actual, tt.billed)
+		}
+		if actual := tt.b.cost(tt.chunk); math.Abs(actual-tt.expected) > 1e-9 {
+			t.Errorf("cost(%+v, %v) = %v, want %v", tt.b, tt.chunk, actual, tt.expected)
+		}
+	}
+}
+
+func TestFilePrice(t *testing.T) {
+	jobs := map[string]job{
+		"video.wav":   {File: "video.wav", Model: "video"},
+		"default.wav": {File: "default.wav", Model: "default"},
+		"lang.wav":    {File: "lang.wav", Language: "de-DE"},
+	}
+
+	tests := []struct {
+		config   *speechpb.RecognitionConfig
+		file     string
+		logging  bool
+		expected float64
+	}{
+		{nil, "a.wav", false, transcribe.StandardPrice},
+		{nil, "a.wav", true, transcribe.StandardLoggingPrice},
+		{nil, "video.wav", false, transcribe.EnhancedPrice},
+		{nil, "video.wav", true, transcribe.EnhancedLoggingPrice},
+		{nil, "lang.wav", false, transcribe.StandardPrice},
+		{&speechpb.RecognitionConfig{Model: "video"}, "a.wav", false, transcribe.EnhancedPrice},
+		{&speechpb.RecognitionConfig{Model: "video"}, "default.wav", false, transcribe.StandardPrice},
+		{&speechpb.RecognitionConfig{Model: "video"}, "lang.wav", false, transcribe.EnhancedPrice},
+		{&speechpb.RecognitionConfig{UseEnhanced: true}, "default.wav", false, transcribe.EnhancedPrice},
+	}
+
+	for _, tt := range tests {
+		opts := options{config: tt.config, jobs: jobs}
+		if actual := filePrice(tt.file, tt.logging, opts); actual != tt.expected {
+			t.Errorf("filePrice(%v, %v, %v) = %v, want %v", tt.file, tt.logging, tt.config, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	"github.com/herohde/transcribe/pkg/diff"
+	"github.com/herohde/transcribe/pkg/util/filex"
+)
+
+// evalMain compares two transcripts of the same audio, such as from different
+// providers or model settings, and writes an HTML report with color-coded
+// differences, which makes accuracy reviews tractable.
+func evalMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("eval", "eval [options] a.txt b.txt", `Eval compares two transcripts of the same audio word by word and writes an
+HTML report with color-coded substitutions, deletions and insertions. If the
+first transcript is a reference transcript, the difference rate is the word
+error rate (WER) of the second.`)
+	out := fs.String("out", "", "Output HTML file. If not provided, the report is written to stdout.")
+	title := fs.String("title", "", "Title of the report. Defaults to the name of the first transcript.")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "Two transcripts must be provided.")
+	}
+	a, b := fs.Arg(0), fs.Arg(1)
+
+	r, err := diff.Compare(a, b, *title)
+	if err != nil {
+		return exitErrorf(exitConfig, "Failed to read transcripts: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteHTML(&buf, r); err != nil {
+		return exitErrorf(exitFailed, "Failed to write report: %v", err)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = filex.WriteFile(*out, buf.Bytes(), 0644, true)
+	}
+	if err != nil {
+		return exitErrorf(exitFailed, "Failed to write report: %v", err)
+	}
+
+	s := diff.Summarize(r.Edits)
+	infof(ctx, "Compared %v words: %v substitutions, %v deletions, %v insertions (%.1f%%)", s.Words, s.Substitutions, s.Deletions, s.Insertions, s.Rate()*100)
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/filex"
+	"google.golang.org/api/googleapi"
+)
+
+// Exit codes, so that wrapping automation can branch on the outcome of a run.
+const (
+	exitOK      = 0
+	exitPartial = 1 // some files failed
+	exitConfig  = 2 // invalid flags or input
+	exitSetup   = 3 // missing or insufficient credentials or failed setup
+	exitBudget  = 4 // estimated cost exceeds budget
+	exitFailed  = 5 // all files failed
+	exitNothing = 6 // no files to transcribe, such as if all are transcribed
+)
+
+// Failure classes.
+const (
+	classQuota    = "quota"
+	classBadAudio = "bad-audio"
+	classInvalid  = "invalid"
+	classTimeout  = "timeout"
+	classAuth     = "auth"
+	classCanceled = "canceled"
+	classOther    = "other"
+)
+
+// failure is a file that failed with a classification of the cause.
+type failure struct {
+	File  string `json:"file,omitempty"`
+	Class string `json:"class"`
+	Error string `json:"error"`
+}
+
+func newFailure(file string, err error) failure {
+	return failure{File: file, Class: classify(err), Error: err.Error()}
+}
+
+// summary is a machine-readable summary of a run.
+type summary struct {
+	Run      string         `json:"run"`
+	Files    int            `json:"files"`
+	Failures []failure      `json:"failures"`
+	Classes  map[string]int `json:"classes"`
+	ExitCode int            `json:"exit_code"`
+}
+
+func newSummary(run string, files int, failures []failure) summary {
+	ret := summary{Run: run, Files: files, Failures: failures, Classes: map[string]int{}, ExitCode: exitCode(files, failures)}
+	for _, f := range failures {
+		ret.Classes[f.Class]++
+	}
+	return ret
+}
+
+// writeSummary writes the summary in JSON format to the given file, or stdout
+// if "-".
+func writeSummary(filename string, s summary, opts options) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return filex.WriteFile(filename, data, 0644, !opts.norename)
+}
+
+// classify returns the failure class of the error.
+func classify(err error) string {
+	switch {
+	case errors.Is(err, transcribe.ErrQuotaExceeded):
+		return classQuota
+	case errors.Is(err, transcribe.ErrBadAudioFormat):
+		return classBadAudio
+	case errors.Is(err, transcribe.ErrInvalidArgument):
+		return classInvalid
+	case errors.Is(err, transcribe.ErrOperationTimeout), errors.Is(err, context.DeadlineExceeded):
+		return classTimeout
+	case isAuth(err):
+		return classAuth
+	case errors.Is(err, context.Canceled):
+		return classCanceled
+	default:
+		return classOther
+	}
+}
+
+// isAuth returns true iff the error is due to missing or insufficient
+// credentials.
+func isAuth(err error) bool {
+	if errors.Is(err, transcribe.ErrPermissionDenied) {
+		return true
+	}
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == http.StatusUnauthorized || gerr.Code == http.StatusForbidden
+	}
+	return false
+}
+
+// exitCode returns the exit code for the failures of a run with the given
+// number of files. Only failures of files count towards all files failing.
+// Other failures, such as of the sink, are partial failures.
+func exitCode(files int, failures []failure) int {
+	if len(failures) == 0 {
+		return exitOK
+	}
+
+	auth := true
+	failed := 0
+	for _, f := range failures {
+		auth = auth && f.Class == classAuth
+		if f.File != "" {
+			failed++
+		}
+	}
+	switch {
+	case auth:
+		return exitSetup
+	case failed >= files:
+		return exitFailed
+	default:
+		return exitPartial
+	}
+}
+
+// exitError is an error of a command with the exit code. If the error is nil,
+// nothing is logged, such as if there is nothing to do.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %v", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitErrorf returns an error with the given exit code.
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// exitStatus logs the error returned by a command, if any, and returns the
+// exit code of the command. Errors without exit code are failures.
+func exitStatus(ctx context.Context, err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var e *exitError
+	if !errors.As(err, &e) {
+		errorf(ctx, "%v", err)
+		return exitFailed
+	}
+	if e.err != nil {
+		errorf(ctx, "%v", e.err)
+	}
+	return e.code
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+func TestExitCode(t *testing.T) {
+	auth := failure{File: "a.wav", Class: classAuth}
+	other := failure{File: "a.wav", Class: classOther}
+	sink := failure{Class: classOther} // not of a file
+
+	tests := []struct {
+		files    int
+		failures []failure
+		expected int
+	}{
+		{2, nil, exitOK},
+		{2, []failure{other}, exitPartial},
+		{2, []failure{other, other}, exitFailed},
+		{1, []failure{other}, exitFailed},
+		{2, []failure{auth, auth}, exitSetup},
+		{2, []failure{auth, other}, exitFailed},
+		{3, []failure{auth, other}, exitPartial},
+		{2, []failure{sink}, exitPartial},
+		{2, []failure{other, sink}, exitPartial},
+		{1, []failure{other, sink}, exitFailed},
+		{1, []failure{sink}, exitPartial},
+	}
+
+	for _, tt := range tests {
+		if actual := exitCode(tt.files, tt.failures); actual != tt.expected {
+			t.Errorf("exitCode(%v, %v) = %v, want %v", tt.files, tt.failures, actual, tt.expected)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{fmt.Errorf("submit: %w", transcribe.ErrQuotaExceeded), classQuota},
+		{fmt.Errorf("submit: %w", transcribe.ErrBadAudioFormat), classBadAudio},
+		{fmt.Errorf("submit: %w", transcribe.ErrBadChannels), classBadAudio},
+		{fmt.Errorf("submit: %w", transcribe.ErrInvalidArgument), classInvalid},
+		{fmt.Errorf("submit: %w", transcribe.ErrOperationTimeout), classTimeout},
+		{context.DeadlineExceeded, classTimeout},
+		{fmt.Errorf("submit: %w", transcribe.ErrPermissionDenied), classAuth},
+		{context.Canceled, classCanceled},
+		{errors.New("other"), classOther},
+	}
+
+	for _, tt := range tests {
+		if actual := classify(tt.err); actual != tt.expected {
+			t.Errorf("classify(%v) = %v, want %v", tt.err, actual, tt.expected)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOK},
+		{exitErrorf(exitConfig, "No files provided."), exitConfig},
+		{&exitError{code: exitNothing}, exitNothing},
+		{fmt.Errorf("wrapped: %w", exitErrorf(exitBudget, "over budget")), exitBudget},
+		{errors.New("other"), exitFailed},
+	}
+
+	for _, tt := range tests {
+		if actual := exitStatus(context.Background(), tt.err); actual != tt.expected {
+			t.Errorf("exitStatus(%v) = %v, want %v", tt.err, actual, tt.expected)
+		}
+	}
+}
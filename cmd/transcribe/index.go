@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/util/filex"
+)
+
+// Index entry statuses.
+const (
+	statusDone    = "done"
+	statusFailed  = "failed"
+	statusSkipped = "skipped" // already transcribed
+	statusInvalid = "invalid" // failed validation
+)
+
+// entry is the result of a single input in the index.
+type entry struct {
+	File     string     `json:"file"`
+	Parts    []string   `json:"parts,omitempty"` // merged files, if any
+	Output   string     `json:"output"`
+	Duration float64    `json:"duration"` // audio duration in seconds, zero if unknown
+	Phrases  int        `json:"phrases"`
+	Engine   string     `json:"engine"`
+	Language string     `json:"language"`
+	Status   string     `json:"status"`
+	Class    string     `json:"class,omitempty"` // failure class, if failed
+	Error    string     `json:"error,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	Elapsed  float64    `json:"elapsed"` // processing time in seconds
+}
+
+func newEntry(file, output, status string, opts options) entry {
+	return entry{
+		File:     file,
+		Output:   output,
+		Engine:   engine,
+		Language: fileLang(file, opts),
+		Status:   status,
+	}
+}
+
+// finish sets the status and timing of a processed entry.
+func (e *entry) finish(start time.Time, phrases int, err error) {
+	e.Start = &start
+	e.Elapsed = time.Since(start).Seconds()
+	e.Phrases = phrases
+	e.Status = statusDone
+	if err != nil {
+		f := newFailure(filepath.Base(e.File), err)
+		e.Status, e.Class, e.Error = statusFailed, f.Class, f.Error
+	}
+}
+
+// index is a machine-readable index of every input of a run, so that
+// downstream automation can consume the results of a batch.
+type index struct {
+	Run     string  `json:"run"`
+	Entries []entry `json:"entries"`
+}
+
+// writeIndex writes the index in JSON format to the given file, or stdout if
+// "-".
+func writeIndex(filename string, idx index, opts options) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return filex.WriteFile(filename, data, 0644, !opts.norename)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/seekerror/logw"
+	"google.golang.org/grpc/grpclog"
+)
+
+// Log levels, in increasing verbosity. Errors and the outcome of a run are
+// always logged.
+const (
+	levelError = iota
+	levelInfo
+	levelDebug
+)
+
+var levels = map[string]int{"error": levelError, "info": levelInfo, "debug": levelDebug}
+
+// logLevel is the log level of the run.
+var logLevel = levelInfo
+
+// logRun is the ID of the run to tag log lines with, if any, so that the lines
+// of concurrent runs can be told apart.
+var logRun string
+
+// setLogLevel sets the log level from --log-level, --quiet and --verbose. At
+// debug level, gRPC logging is enabled as well.
+func setLogLevel(level string, quiet, verbose bool) error {
+	if quiet && verbose {
+		return fmt.Errorf("--quiet and --verbose cannot both be given")
+	}
+	l, ok := levels[strings.ToLower(level)]
+	if !ok {
+		return fmt.Errorf("invalid log level: %v. Must be error, info or debug", level)
+	}
+	switch {
+	case quiet:
+		l = levelError
+	case verbose:
+		l = levelDebug
+	}
+	logLevel = l
+
+	if logLevel >= levelDebug {
+		grpclog.SetLoggerV2(grpclog.NewLoggerV2WithVerbosity(os.Stderr, os.Stderr, os.Stderr, 2))
+	}
+	return nil
+}
+
+// infof logs progress, unless quiet.
+func infof(ctx context.Context, format string, args ...interface{}) {
+	if logLevel >= levelInfo {
+		logw.Infof(ctx, tag(format), args...)
+	}
+}
+
+// debugf logs details for debugging, such as API requests, if verbose.
+func debugf(ctx context.Context, format string, args ...interface{}) {
+	if logLevel >= levelDebug {
+		logw.Infof(ctx, tag("DEBUG: "+format), args...)
+	}
+}
+
+// errorf logs a failure.
+func errorf(ctx context.Context, format string, args ...interface{}) {
+	logw.Errorf(ctx, tag(format), args...)
+}
+
+// tag prefixes the log format with the run ID, if any.
+func tag(format string) string {
+	if logRun == "" {
+		return format
+	}
+	return "[" + logRun + "] " + format
+}
@@ -4,183 +4,1789 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"syscall"
+	"text/template"
 	"time"
 
 	"cloud.google.com/go/speech/apiv1"
+	"github.com/herohde/transcribe/pkg/audio"
+	"github.com/herohde/transcribe/pkg/audio/flac"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/cache"
+	"github.com/herohde/transcribe/pkg/fetch"
+	"github.com/herohde/transcribe/pkg/format"
+	"github.com/herohde/transcribe/pkg/notify"
+	"github.com/herohde/transcribe/pkg/sink"
 	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/drivex"
+	"github.com/herohde/transcribe/pkg/util/filex"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+	"github.com/herohde/transcribe/pkg/util/retryx"
 	"github.com/herohde/transcribe/pkg/util/storagex"
 	"github.com/seekerror/build"
 	"github.com/seekerror/logw"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/storage/v1"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
+// defaultSampleRate is the rate in Hz that audio at unsupported rates is
+// resampled to.
+const defaultSampleRate = 16000
+
+// options are the processing options for each file.
+type options struct {
+	mono      bool
+	separate  bool
+	low       float32
+	showconf  bool
+	redact    []string
+	edl       bool
+	cache     *cache.Cache
+	responses bool // API responses cached, if cache is set
+	retry     retryx.Policy
+	ops       chan struct{} // nil if unlimited
+	interval  time.Duration
+	run       string
+	config    *speechpb.RecognitionConfig
+	markers   []string
+	pause     time.Duration   // no turns if zero
+	gap       time.Duration   // no paragraphs if zero
+	converter audio.Converter // nil if auto
+	factor    float64
+	margin    time.Duration
+	sink      sink.Sink // nil if none
+	flac      bool
+	silence   wav.SilenceOptions // trimmed if MinDuration > 0
+	chunk     time.Duration      // not split if zero
+	loudnorm  bool
+	rate      int         // only unsupported rates resampled if zero
+	track     int         // default track if zero
+	selected  []int       // all channels if empty
+	pcm       *wav.Header // format of headerless PCM files, nil if not given
+	raw       string      // not archived if empty
+	subdir    string      // relative output directory of the file, if mirrored
+	norename  bool
+	bom       bool
+	crlf      bool
+	chapters  bool
+	speakers  bool
+	words     bool
+	format    format.Formatter
+	player    bool
+	layout    format.Options
+	durations map[string]time.Duration // probed duration by file, zero if unknown
+	metadata  map[string]string
+	outputs   map[string]string  // output path by file
+	out       string             // output directory, or "-" if stdout
+	template  *template.Template // output name template, nil if named after the file
+	date      time.Time          // date of the run in output names
+	suffix    bool               // outputs versioned, such as foo.wav.v2.txt
+	dirs      map[string]string  // relative output directory by file found in a directory argument
+	jobs      map[string]job     // manifest job by file, if any
+	bucket    string             // tmp bucket created if empty
+	storage   *storage.Service   // GCS client, if created
+	bars      *bars              // progress logged if nil
+}
+
 var (
-	project = flag.String("project", "", "GCP project to use. The project must have the Speech API enabled.")
-	output  = flag.String("out", ".", "Directory to place output text files.")
-	bucket  = flag.String("bucket", "", "Temporary GCS bucket to hold the audio files. If not provided, a new transient bucket will be created.")
-	mono    = flag.Bool("mono", false, "Convert stereo audio file to mono (required if stereo).")
+	project  = flag.String("project", "", "GCP project to use. The project must have the Speech API enabled.")
+	output   = flag.String("out", ".", "Directory to place output text files. Use '-' to write the transcript of a single file to stdout.")
+	bucket   = flag.String("bucket", "", "Temporary GCS bucket to hold the audio files. If not provided, a new transient bucket will be created.")
+	mono     = flag.Bool("mono", false, "Convert audio to mono. Stereo wav and flac files are converted automatically, unless --separate-channels is given.")
+	separate = flag.Bool("separate-channels", false, "Recognize the channels of stereo or multichannel wav and flac files separately instead of downmixing them, such as for call recordings with a channel per party. Each channel is billed. Use --turns to break the transcript at channel switches.")
+	low      = flag.Float64("low-confidence", 0, "Mark words below the given confidence threshold as '[low: word]' in the output (0 to disable).")
+	showconf = flag.Bool("show-confidence", false, "Annotate each phrase or paragraph with its confidence, such as '(0.72)', so that reviewers can prioritize low-confidence sections.")
+	redact   = flag.String("redact", "", "Comma-separated list of keywords to redact from the output.")
+	edl      = flag.Bool("redact-edl", false, "Write a time-coded redaction list (e.g., foo.wav.edl) next to the output for bleeping the audio.")
+	retries  = flag.Int("retries", retryx.DefaultPolicy.Attempts, "Maximum number of attempts for API calls failing with transient errors.")
+	folder   = flag.String("drive-folder", "", "Google Drive folder ID to transcribe new audio files from. Transcripts are written back into the folder. Replaces file arguments.")
+	dcreds   = flag.String("drive-credentials", "", "Service account key file for Google Drive access. If not provided, application default credentials are used.")
+	docs     = flag.Bool("drive-docs", false, "Write transcripts back to Google Drive as Google Docs instead of text files.")
+	maxops   = flag.Int("max-operations", 0, "Maximum number of concurrent transcription operations. Additional files are queued. If zero, no limit is imposed.")
+	factor   = flag.Float64("timeout-factor", 2, "Per-file timeout as multiple of the audio duration, in addition to the timeout margin.")
+	margin   = flag.Duration("timeout-margin", 30*time.Minute, "Per-file timeout margin in addition to the scaled audio duration.")
+	interval = flag.Duration("progress-interval", time.Minute, "Minimum interval between progress log lines for each file.")
+	sections = flag.String("sections", "", "Comma-separated list of spoken section markers, such as 'next agenda item', to split the transcript into timestamped sections.")
+	outfmt   = flag.String("format", "text", "Output format: text (foo.wav.txt), srt (foo.wav.srt) and vtt (foo.wav.vtt) subtitles and lrc (foo.wav.lrc) lyrics timed by word offsets, json (foo.wav.json) with phrases, words, offsets, confidence, speakers and channels, jsonl (foo.wav.jsonl) with such a JSON object per phrase, csv (foo.wav.csv) with start, end, speaker, confidence and text per phrase, md (foo.wav.md) with timestamp headings and speaker-labelled paragraphs, or html (foo.wav.html) with time-linked words for review.")
+	outtmpl  = flag.String("out-template", "", "Template for output file names, such as '{{.Basename}}.{{.Lang}}.{{.Format}}' for foo.en-US.txt. Fields: Name (foo.wav), Basename (foo), Ext (wav), Format (txt), Lang, Engine, Date (2006-01-02) and Run. If not provided, outputs are named foo.wav.txt, etc.")
+	player   = flag.Bool("html-audio", false, "Embed an audio player for the source file in --format=html pages, so that clicking a word plays the audio from there.")
+	anchors  = flag.Duration("md-interval", transcribe.DefaultAnchorInterval, "Minimum interval between timestamp headings for --format=md.")
+	maxchars = flag.Int("max-line-chars", transcribe.DefaultMaxLineChars, "Maximum number of characters per subtitle line for --format=srt, vtt or lrc.")
+	maxlines = flag.Int("max-cue-lines", transcribe.DefaultMaxCueLines, "Maximum number of lines per subtitle cue for --format=srt or vtt. Lines are wrapped on word boundaries to be of similar length.")
+	maxcue   = flag.Duration("max-cue-duration", transcribe.DefaultMaxCueDuration, "Maximum duration of a subtitle cue for --format=srt, vtt or lrc.")
+	voices   = flag.Bool("voice-tags", false, "Tag WebVTT cues with the speaker, such as <v Speaker 1>, if diarization is enabled.")
+	words    = flag.Bool("words", false, "Also write the words with start, end, confidence and speaker as JSON, such as foo.wav.words.json, regardless of the output format.")
+	speakers = flag.Bool("per-speaker", false, "Also write a transcript per speaker, such as foo.wav.speaker1.txt, if diarization is enabled.")
+	chaps    = flag.Bool("chapters", false, "Also write a transcript per chapter, such as foo.wav.01.txt, for wav files with cue points and videos with chapter markers. Chapter markers are read with ffprobe.")
+	paras    = flag.Duration("paragraphs", 0, "Break the transcript into plain paragraphs at pauses between words of at least the given duration, such as 2s. If zero, the transcript is a single block, unless diarized.")
+	turns    = flag.Duration("turns", 0, "Break the transcript into paragraphs at likely speaker turns: pauses of at least the given duration, such as 1.5s, speaker changes and channel switches. If zero, the transcript is a single block.")
+	cfgfile  = flag.String("config", "", "YAML file with options, such as 'project: myproject', keyed by flag name. Flags given on the command line take precedence. If not provided, ./transcribe.yaml is used, if present.")
+	language = flag.String("language", "", "Language code of the audio, such as de-DE. If not provided, en-US is assumed, unless set in --config-json.")
+	model    = flag.String("model", "", "Recognition model, such as 'video'. If not provided, the default model is used, unless set in --config-json.")
+	hints    = flag.String("hints", "", "Comma-separated list of phrases, such as names or jargon, that are likely in the audio to improve recognition.")
+	config   = flag.String("config-json", "", "File with a Speech API v1 RecognitionConfig in JSON format to use as base config. Explicit flags take precedence.")
+	notifs   = repeated("notify", "Notifier for completion or failure, such as 'slack:<webhook url>'. Repeat for multiple notifiers. Supported: webhook:<url>, slack:<url>, email:<smtp url>, pubsub:projects/<project>/topics/<topic>.")
+	runID    = flag.String("run-id", "", "Identifier of this run, used in logs and GCS object names. If not provided, a unique ID is generated.")
+	conv     = flag.String("converter", "auto", "Converter for transcoding and stereo conversion: native (wav only), sox, ffmpeg or auto to use the first that can convert the file.")
+	toflac   = flag.Bool("flac", false, "Compress wav files losslessly to flac before upload to reduce upload size. Requires sox or ffmpeg.")
+	trimmin  = flag.Duration("trim-silence", 0, "Remove silences longer than the given duration, such as 5s, from wav files before upload to reduce billed audio. Word offsets still refer to the original audio. If zero, no silence is removed.")
+	trimlvl  = flag.Float64("silence-threshold", -40, "Level in dBFS below which audio is considered silent for --trim-silence and --chunk.")
+	trimpad  = flag.Duration("silence-padding", 500*time.Millisecond, "Silence kept next to audio for --trim-silence.")
+	loudnorm = flag.Bool("normalize", false, "Normalize the loudness of quiet recordings before upload using the converter: peak level with sox, EBU R128 with ffmpeg.")
+	resample = flag.Int("sample-rate", 0, "Resample audio to the given rate in Hz, such as 16000, before upload. If zero, only audio at rates unsupported by the API is resampled (to 16kHz).")
+	pcmrate  = flag.Int("pcm-rate", 0, "Sample rate in Hz of headerless .pcm or .raw files, such as audio dumped from embedded devices. Required for such files.")
+	pcmchans = flag.Int("pcm-channels", 1, "Number of interleaved channels of headerless .pcm or .raw files.")
+	pcmbits  = flag.Int("pcm-bits", 16, "Bits per sample of headerless .pcm or .raw files: 8 (unsigned), 16, 24 or 32 (signed little-endian).")
+	pcmenc   = flag.String("pcm-encoding", "linear", "Encoding of headerless .pcm or .raw files: linear (with --pcm-bits), mulaw or alaw (8-bit G.711, such as SIP captures).")
+	track    = flag.Int("track", 0, "Audio track to transcribe, 1-based, for files with multiple audio tracks, such as videos with a commentary track. Requires ffmpeg. If zero, the default track is used.")
+	chans    = flag.String("channels", "", "Comma-separated list of channels to transcribe, 1-based, such as '3' for a single speaker of a multitrack session export. Selected channels are mixed to mono. If not provided, all channels are used.")
+	include  = flag.String("include", "", "Comma-separated list of patterns, such as '*.wav,interview-*', of files to transcribe when searching directories or expanding glob patterns. Patterns with a path separator are matched against the path, others against the file name.")
+	exclude  = flag.String("exclude", "", "Comma-separated list of patterns, such as 'drafts/**', of files to skip when searching directories or expanding glob patterns.")
+	merge    = flag.Bool("merge", false, "Transcribe the files as sequential parts of one recording, such as a recording split by the recorder at 2GB, into a single transcript named after the first file. Offsets are relative to the whole recording.")
+	chunk    = flag.Duration("chunk", 0, "Split wav files longer than the given duration, such as 30m, at silences into chunks that are transcribed in parallel. If zero, files are not split.")
+	sinks    = flag.String("sink", "", "Comma-separated list of sinks to store transcripts in for analysis, such as 'parquet:words.parquet'. Supported: parquet:<file>, bigquery:[<project>.]<dataset>.<table>.")
+	rawdir   = flag.String("raw-dir", "", "Directory to archive the raw API responses in as JSON, such as foo.wav.response.json, so that transcripts can be reprocessed without transcribing again. If not provided, responses are not archived.")
+	dump     = flag.Bool("dump-response", false, "Shorthand for --raw-dir set to the output directory, so the full API response is written next to the output.")
+	objmeta  = flag.String("object-metadata", "", "Comma-separated list of key=value pairs of custom metadata to attach to staged GCS objects, in addition to the run ID, source path and hash.")
+	bom      = flag.Bool("bom", false, "Write transcripts with a UTF-8 byte order mark, which some Windows captioning tools require. Text and subtitle formats only.")
+	crlf     = flag.Bool("crlf", false, "Write transcripts with CRLF line endings, which some Windows captioning tools require. Text and subtitle formats only.")
+	norename = flag.Bool("no-rename", false, "Write output files in place instead of via a renamed temporary file, for shared volumes without atomic rename. Output files are locked either way.")
+	force    = flag.Bool("force", false, "Transcribe files again and overwrite their output, if it exists, instead of skipping them.")
+	suffix   = flag.Bool("suffix", false, "Transcribe files again and write a new versioned output, such as foo.wav.v2.txt, if the output exists, instead of skipping them.")
+	skip     = flag.Bool("skip-invalid", false, "Skip audio files that fail validation instead of exiting before transcribing any files.")
+	budget   = flag.Float64("budget", 0, "Maximum estimated cost in USD of transcribing the files. If exceeded, no files are transcribed. If zero, no limit is imposed.")
+	indexf   = flag.String("index", "", "File to write a machine-readable JSON index of every input to, such as index.json, with output path, duration, phrase count, engine, language, status and timing. Use '-' for stdout.")
+	summ     = flag.String("summary", "", "File to write a machine-readable JSON summary of the run to, including classified failures. Use '-' for stdout.")
+	dryrun   = flag.Bool("estimate", false, "Only report the total audio duration, estimated cost and time of transcribing the files. No files are transcribed.")
+	stdin    = flag.Bool("stdin", false, "Also read newline-delimited files to transcribe from stdin, such as: find . -name '*.wav' | transcribe --stdin.")
+	progress = flag.Bool("progress", true, "Show progress bars per file and for the batch instead of progress log lines. Progress is logged if stderr is not a terminal.")
+	manifest = flag.String("manifest", "", "CSV or JSON file listing the files to transcribe with per-file options: language, model, output name and hints. CSV files have a header row naming the columns file, language, model, output and hints, with hints separated by semicolons. Relative files are resolved against the manifest directory.")
+	loglevel = flag.String("log-level", "info", "Log level: error, to only log failures and the outcome of the run, info or debug, to also log API requests and gRPC details.")
+	quiet    = flag.Bool("quiet", false, "Only log failures and the outcome of the run, such as for cron jobs. Same as --log-level=error.")
+	verbose  = flag.Bool("verbose", false, "Also log API requests and gRPC details for debugging. Same as --log-level=debug.")
+	cachedir = flag.String("cache", "", "Directory to cache converted audio files, so that re-running a batch skips conversion. If not provided, no caching is done.")
+	cacheapi = flag.Bool("cache-responses", false, "Also cache API responses in --cache by the content hash of the audio and the recognition settings, so that re-running a batch, such as while developing output formats, does not transcribe the files again. GCS objects are keyed by their MD5 or CRC32C hash.")
 
 	version = build.NewVersion(0, 9, 0)
+
+	runIDRE = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
 )
 
 func init() {
 	flag.Usage = func() {
-		fmt.Fprint(os.Stderr, `usage: transcribe [options] file [...]
+		fmt.Fprint(os.Stderr, `usage: transcribe [run] [options] file [...]
+       transcribe <command> [options] [args]
 
 Transcribe transcribes audio files using Google Speech API. It is intended
 for bulk processing of large (> 1 min) audio files and automates GCS upload
-(and removal). Supported format: wav 44.1kHz (stereo or mono).
-Options:
+(and removal). Supported formats: wav 8-48kHz (stereo or mono, including
+µ-law/A-law telephony audio), flac,
+mp3 (transcoded using sox or ffmpeg), m4a/aac and mp4/mkv/mov video
+(transcoded using ffmpeg).
+Commands:
 `)
+		printCommands(os.Stderr)
+		fmt.Fprint(os.Stderr, "Options of run:\n")
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
-	flag.Parse()
 	ctx := context.Background()
-	logw.Infof(ctx, "Transcribe, build %v", version)
+
+	args := os.Args[1:]
+	cmd := runMain // default command
+	if len(args) > 0 {
+		if c, ok := lookupCommand(args[0]); ok {
+			cmd, args = c.main, args[1:]
+		}
+	}
+	os.Exit(exitStatus(ctx, cmd(ctx, args)))
+}
+
+// runMain transcribes the audio files given by the arguments.
+func runMain(ctx context.Context, args []string) error {
+	flag.CommandLine.Parse(args)
+
+	file, required := *cfgfile, true
+	if file == "" {
+		file, required = defaultConfigFile, false
+	}
+	if err := applyConfigFile(flag.CommandLine, file, required); err != nil {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid config file: %v", err)
+	}
+	if err := setLogLevel(*loglevel, *quiet, *verbose); err != nil {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid log level: %v", err)
+	}
+
+	id := *runID
+	if id == "" {
+		id = newRunID()
+	}
+	if !runIDRE.MatchString(id) {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid run ID: %v. Must be lowercase letters, digits and dashes.", id)
+	}
+	logRun = id
+	infof(ctx, "Transcribe, build %v, run %v", version, id)
 
 	// (1) Validate input
-	if len(flag.Args()) == 0 {
+	names := flag.Args()
+	if *stdin {
+		list, err := readFiles(os.Stdin)
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to read files from stdin: %v", err)
+		}
+		names = append(names, list...)
+	}
+
+	if len(names) == 0 && *folder == "" && *manifest == "" {
+		flag.Usage()
+		return exitErrorf(exitConfig, "No files provided.")
+	}
+	if len(names) > 0 && *folder != "" {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Files cannot be provided with a Drive folder.")
+	}
+	if *manifest != "" && (len(names) > 0 || *folder != "") {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Files cannot be provided with a manifest.")
+	}
+	if *manifest != "" && *merge {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Manifest cannot be used with --merge.")
+	}
+	if *project == "" && !*dryrun {
+		flag.Usage()
+		return exitErrorf(exitConfig, "No project provided.")
+	}
+	if *low < 0 || *low > 1 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid confidence threshold: %v. Must be in [0;1].", *low)
+	}
+	if *retries < 1 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid number of attempts: %v. Must be at least 1.", *retries)
+	}
+	if *maxops < 0 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid number of concurrent operations: %v", *maxops)
+	}
+	if *factor <= 0 || *margin < 0 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid timeout: factor %v and margin %v must be positive.", *factor, *margin)
+	}
+	if *edl && *redact == "" {
+		flag.Usage()
+		return exitErrorf(exitConfig, "No keywords to redact provided for redaction list.")
+	}
+	if *turns < 0 || *paras < 0 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid pause: %v and %v must not be negative.", *turns, *paras)
+	}
+	if *maxchars < 1 || *maxlines < 1 || *maxcue <= 0 {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Invalid subtitle limits: %v characters per line, %v lines and %v per cue must be positive.", *maxchars, *maxlines, *maxcue)
+	}
+	if *force && *suffix {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Cannot both overwrite and version outputs.")
+	}
+	if *separate && (*mono || *chans != "") {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Separate channels cannot be used with --mono or --channels.")
+	}
+	if *merge && (*folder != "" || *chaps) {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Merged files cannot be used with Drive folders or chapters.")
+	}
+	if *output == "-" && (*folder != "" || *edl || *words || *speakers || *chaps || *dump || *summ == "-" || *indexf == "-") {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Output to stdout cannot be used with Drive folders or additional output files.")
+	}
+	raw := *rawdir
+	if *dump {
+		if raw != "" && raw != *output {
+			flag.Usage()
+			return exitErrorf(exitConfig, "Cannot both dump responses next to the output and archive them in --raw-dir.")
+		}
+		raw = *output
+	}
+
+	opts := options{
+		mono:     *mono,
+		separate: *separate,
+		low:      float32(*low),
+		showconf: *showconf,
+		edl:      *edl,
+		interval: *interval,
+		pause:    *turns,
+		gap:      *paras,
+		run:      id,
+		factor:   *factor,
+		margin:   *margin,
+		flac:     *toflac,
+		silence:  wav.SilenceOptions{Threshold: *trimlvl, MinDuration: *trimmin, Padding: *trimpad},
+		chunk:    *chunk,
+		loudnorm: *loudnorm,
+		rate:     *resample,
+		track:    *track,
+		raw:      raw,
+		norename: *norename,
+		bom:      *bom,
+		crlf:     *crlf,
+		chapters: *chaps,
+		speakers: *speakers,
+		words:    *words,
+		player:   *player,
+		out:      *output,
+		date:     time.Now(),
+		suffix:   *suffix,
+		dirs:     map[string]string{},
+		jobs:     map[string]job{},
+		bucket:   *bucket,
+	}
+	opts.layout = format.Options{
+		Subtitles:      transcribe.SubtitleOptions{MaxLineChars: *maxchars, MaxLines: *maxlines, MaxDuration: *maxcue},
+		Voices:         *voices,
+		AnchorInterval: *anchors,
+	}
+	opts.retry = retryx.DefaultPolicy
+	opts.retry.Attempts = *retries
+	if *maxops > 0 {
+		opts.ops = make(chan struct{}, *maxops)
+	}
+	if *redact != "" {
+		opts.redact = strings.Split(*redact, ",")
+	}
+	if *objmeta != "" {
+		opts.metadata = map[string]string{}
+		for _, kv := range strings.Split(*objmeta, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				flag.Usage()
+				return exitErrorf(exitConfig, "Invalid object metadata: %v. Must be key=value.", kv)
+			}
+			opts.metadata[parts[0]] = parts[1]
+		}
+	}
+	if *conv != "auto" {
+		cv, err := audio.Lookup(*conv)
+		if err != nil {
+			flag.Usage()
+			return exitErrorf(exitConfig, "Invalid converter: %v", err)
+		}
+		opts.converter = cv
+	}
+
+	if *pcmrate > 0 {
+		h, err := pcmFormat(*pcmenc, *pcmrate, *pcmchans, *pcmbits)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid PCM format: %v", err)
+		}
+		opts.pcm = h
+	}
+	f, err := format.Lookup(*outfmt)
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid --format: %v", err)
+	}
+	opts.format = f
+	if (*bom || *crlf) && !encoded[f.Name()] {
+		flag.Usage()
+		return exitErrorf(exitConfig, "Byte order marks and CRLF line endings apply only to text and subtitle formats, not %v.", f.Name())
+	}
+	if *chans != "" {
+		list, err := parseChannels(*chans)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid channels: %v", err)
+		}
+		opts.selected = list
+	}
+	if *sections != "" {
+		opts.markers = strings.Split(*sections, ",")
+	}
+	if *config != "" {
+		c, err := transcribe.LoadConfig(*config)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid config: %v", err)
+		}
+		opts.config = c
+	}
+	if *language != "" || *model != "" || *hints != "" {
+		if opts.config == nil {
+			opts.config = &speechpb.RecognitionConfig{}
+		}
+		if *language != "" {
+			opts.config.LanguageCode = *language
+		}
+		if *model != "" {
+			opts.config.Model = *model
+		}
+		if *hints != "" {
+			opts.config.SpeechContexts = append(opts.config.SpeechContexts, &speechpb.SpeechContext{Phrases: strings.Split(*hints, ",")})
+		}
+	}
+	caps := transcribe.GoogleCapabilities()
+	if *outtmpl != "" {
+		tmpl, err := parseOutputTemplate(*outtmpl, opts)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid --out-template: %v", err)
+		}
+		opts.template = tmpl
+	}
+	if raw != "" {
+		if err := os.MkdirAll(raw, 0755); err != nil {
+			return exitErrorf(exitConfig, "Invalid raw response directory: %v", err)
+		}
+	}
+	if *cacheapi && *cachedir == "" {
 		flag.Usage()
-		logw.Exitf(ctx, "No files provided.")
+		return exitErrorf(exitConfig, "No cache directory provided for API responses.")
+	}
+	if *cachedir != "" {
+		c, err := cache.New(*cachedir)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid cache: %v", err)
+		}
+		opts.cache = c
+		opts.responses = *cacheapi
+	}
+
+	var notifiers notify.Multi
+	for _, spec := range *notifs {
+		if *dryrun {
+			break // not notified
+		}
+		n, err := notify.Parse(ctx, spec)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid notifier: %v", err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	defer notifiers.Close()
+
+	if *sinks != "" && !*dryrun {
+		var m sink.Multi
+		for _, spec := range strings.Split(*sinks, ",") {
+			sk, err := sink.Parse(ctx, spec)
+			if err != nil {
+				return exitErrorf(exitConfig, "Invalid sink: %v", err)
+			}
+			m = append(m, sk)
+		}
+		opts.sink = m
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	sel := selection{include: *include, exclude: *exclude}
+
+	var cleanup func()
+	if *manifest != "" {
+		args, cleanup, err = manifestInputs(ctx, *manifest, sel, opts.dirs, opts.jobs)
+	} else {
+		args, cleanup, err = inputs(ctx, names, sel, opts.dirs)
 	}
-	if *project == "" {
+	if err != nil {
 		flag.Usage()
-		logw.Exitf(ctx, "No project provided.")
+		return exitErrorf(exitConfig, "Invalid files: %v", err)
+	}
+	defer cleanup()
+
+	var dcl *drive.Service
+	if *folder != "" {
+		// Drive mode: download new audio files to a local tmp directory, which
+		// is also used for the output.
+
+		dcl, err = drivex.NewClient(ctx, *dcreds)
+		if err != nil {
+			return exitErrorf(exitSetup, "Failed to create Drive client: %v", err)
+		}
+		if *dryrun {
+			// Estimate from the file metadata without downloading the files.
+
+			list, err := newDriveFiles(ctx, dcl, *folder, opts)
+			if err != nil {
+				return exitErrorf(exitSetup, "Failed to list audio files in Drive: %v", err)
+			}
+			infof(ctx, "Found %v new audio files in Drive folder %v", len(list), *folder)
+			estimateDrive(ctx, dcl, list, opts)
+			return nil // exit: estimate only
+		}
+
+		dir, err := ioutil.TempDir("", "transcribe")
+		if err != nil {
+			return exitErrorf(exitSetup, "Failed to create tmp directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		args, err = fetchDrive(ctx, dcl, *folder, dir, opts)
+		if err != nil {
+			return exitErrorf(exitSetup, "Failed to fetch audio files from Drive: %v", err)
+		}
+		opts.out = dir
+
+		infof(ctx, "Found %v new audio files in Drive folder %v", len(args), *folder)
+	}
+
+	targets := args
+	if *merge {
+		targets = args[:1] // parts are written to the output of the first
+	}
+	outs, err := outputPaths(targets, opts)
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid output names: %v", err)
 	}
+	opts.outputs = outs
+
+	idx := index{Run: id}
 
 	var files []string
-	for _, file := range flag.Args() {
-		if !strings.HasSuffix(strings.ToLower(file), ".wav") {
+	for _, file := range args {
+		if !isSupported(file) {
 			flag.Usage()
-			logw.Exitf(ctx, "File %v is not a supported (.wav) format", file)
+			return exitErrorf(exitConfig, "File %v is not a supported format: %v", file, strings.Join(formats(), ", "))
+		}
+		if isObject(file) && *merge {
+			flag.Usage()
+			return exitErrorf(exitConfig, "GCS object %v cannot be merged. Use local files with --merge.", file)
 		}
 
-		out := filepath.Join(*output, filepath.Base(file)+".txt")
-		if _, err := os.Stat(out); err == nil || !os.IsNotExist(err) {
-			logw.Infof(ctx, "File %v already transcribed. Ignoring.", file)
+		out := opts.outputs[file]
+		if *merge {
+			out = opts.outputs[args[0]]
+		}
+		if out == "-" {
+			if len(args) > 1 && !*merge {
+				return exitErrorf(exitConfig, "Output to stdout requires a single file, got %v.", len(args))
+			}
+		} else if _, err := os.Stat(out); err == nil && *force {
+			infof(ctx, "File %v already transcribed. Overwriting.", file)
+		} else if err == nil && filex.Partial(out) {
+			infof(ctx, "File %v has incomplete output %v. Transcribing again.", file, out)
+		} else if err == nil || !os.IsNotExist(err) {
+			infof(ctx, "File %v already transcribed. Ignoring.", file)
+			idx.Entries = append(idx.Entries, newEntry(file, out, statusSkipped, opts))
 			continue
 		}
 
 		files = append(files, file)
 	}
 	if len(files) == 0 {
-		return // exit: nothing to do
+		if opts.sink != nil {
+			opts.sink.Close()
+		}
+		if *summ != "" {
+			sum := newSummary(id, 0, nil)
+			sum.ExitCode = exitNothing
+			if err := writeSummary(*summ, sum, opts); err != nil {
+				errorf(ctx, "Failed to write summary: %v", err)
+			}
+		}
+		if *indexf != "" {
+			if err := writeIndex(*indexf, idx, opts); err != nil {
+				errorf(ctx, "Failed to write index: %v", err)
+			}
+		}
+		logw.Infof(ctx, "No audio files to transcribe in run %v", id)
+		return &exitError{code: exitNothing} // exit: nothing to do
 	}
 
-	// (2) Create GCP clients
-
-	cl, err := storagex.NewClient(context.Background())
-	if err != nil {
-		logw.Fatalf(ctx, "Failed to create GCS client: %v", err)
+	for _, file := range files {
+		if isObject(file) && opts.storage == nil {
+			cl, err := storagex.NewClient(ctx)
+			if err != nil {
+				return exitErrorf(exitSetup, "Failed to create GCS client: %v", err)
+			}
+			opts.storage = cl // object headers are read before transcribing
+		}
 	}
-	scl, err := speech.NewClient(context.Background())
-	if err != nil {
-		logw.Fatalf(ctx, "Failed to create speech client: %v", err)
+
+	// Probe the audio durations once for validation, estimation and progress.
+
+	opts.durations = map[string]time.Duration{}
+	for _, file := range files {
+		opts.durations[file] = probe(ctx, file, opts)
 	}
 
-	// (3) Create tmp location, if needed.
+	if problems := validate(ctx, files, caps, opts); len(problems) > 0 {
+		printProblems(os.Stderr, problems)
+		if !*skip || *merge {
+			return exitErrorf(exitConfig, "Found %v invalid audio files. Exiting.", len(problems))
+		}
 
-	if *bucket == "" {
-		*bucket = fmt.Sprintf("transcribe-%v", time.Now().UnixNano())
+		invalid := map[string]bool{}
+		for _, p := range problems {
+			invalid[p.File] = true
 
-		if err := storagex.NewBucket(cl, *project, *bucket); err != nil {
-			logw.Fatalf(ctx, "Failed to create tmp bucket %v: %v", *bucket, err)
+			e := newEntry(p.File, opts.outputs[p.File], statusInvalid, opts)
+			e.Error = p.Problem
+			idx.Entries = append(idx.Entries, e)
+		}
+		var valid []string
+		for _, file := range files {
+			if !invalid[file] {
+				valid = append(valid, file)
+			}
 		}
-		defer storagex.TryDeleteBucket(ctx, cl, *bucket)
+		infof(ctx, "Skipping %v invalid audio files", len(problems))
 
-		logw.Infof(ctx, "Using temporary GCS bucket '%v'", *bucket)
+		files = valid
+		if len(files) == 0 {
+			return exitErrorf(exitFailed, "No valid audio files. Exiting.")
+		}
 	}
 
-	logw.Infof(ctx, "Transcribing %v audio files in parallel", len(files))
+	cost := estimate(ctx, files, opts)
+	if *budget > 0 && cost > *budget {
+		return exitErrorf(exitBudget, "Estimated cost $%.2f exceeds budget $%.2f. Exiting.", cost, *budget)
+	}
+	if *dryrun {
+		return nil // exit: estimate only
+	}
 
-	// (4) Upload, transcribe and process the files in parallel
+	failures, entries, err := run(ctx, files, opts)
+	if err != nil {
+		return exitErrorf(exitSetup, "Failed to set up run %v: %v", id, err)
+	}
+	idx.Entries = append(idx.Entries, entries...)
+	if opts.sink != nil {
+		if err := opts.sink.Close(); err != nil {
+			errorf(ctx, "Failed to close sink: %v", err)
+			failures = append(failures, newFailure("", err))
+		}
+	}
+	if dcl != nil {
+		failures = append(failures, publishDrive(ctx, dcl, *folder, files, *docs, opts)...)
+	}
 
-	var failures int32
+	// (5) Notify and summarize
 
-	var wg sync.WaitGroup
-	for _, name := range files {
-		wg.Add(1)
-		go func(filename string) {
-			defer wg.Done()
+	e := notify.Event{Run: id, Status: notify.Success, Files: len(files), Failures: len(failures), Time: time.Now()}
+	if len(failures) > 0 {
+		e.Status = notify.Failure
+	}
+	if err := notifiers.Notify(context.WithoutCancel(ctx), e); err != nil {
+		errorf(ctx, "Failed to send notification: %v", err)
+	}
 
-			name := filepath.Base(filename)
-			out := filepath.Join(*output, name+".txt")
+	n := len(files)
+	if *merge {
+		n = 1 // parts fail together
+	}
+	sum := newSummary(id, n, failures)
+	if *summ != "" {
+		if err := writeSummary(*summ, sum, opts); err != nil {
+			errorf(ctx, "Failed to write summary: %v", err)
+		}
+	}
+	if *indexf != "" {
+		if err := writeIndex(*indexf, idx, opts); err != nil {
+			errorf(ctx, "Failed to write index: %v", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return exitErrorf(sum.ExitCode, "Failed to transcribe %v audio files in run %v (%v). Exiting.", len(failures), id, sum.Classes)
+	}
+	logw.Infof(ctx, "Done with run %v: transcribed %v audio files", id, len(files))
+	return nil
+}
 
-			logw.Infof(ctx, "Transcribing %v ...", name)
+// multiFlag is a repeatable flag, whose values may contain commas.
+type multiFlag []string
 
-			if err := process(context.Background(), scl, cl, *bucket, filename, out, *mono); err != nil {
-				logw.Errorf(ctx, "Failed to process %v: %v", name, err)
-				atomic.AddInt32(&failures, 1)
-				return
-			}
+// repeated defines a repeatable flag with the given name and usage.
+func repeated(name, usage string) *multiFlag {
+	ret := &multiFlag{}
+	flag.Var(ret, name, usage)
+	return ret
+}
 
-			logw.Infof(ctx, "Transcribed %v", name)
-		}(name)
+func (m *multiFlag) String() string {
+	if m == nil {
+		return ""
 	}
-	wg.Wait()
+	return strings.Join(*m, " ")
+}
 
-	if failures > 0 {
-		logw.Fatalf(ctx, "Failed to transcribe %v audio files. Exiting.", failures)
+func (m *multiFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// pcmFormat returns the format of headerless PCM files with the given encoding:
+// linear, mulaw or alaw. The bits per sample only apply to linear PCM.
+func pcmFormat(encoding string, rate, channels, bits int) (*wav.Header, error) {
+	h := &wav.Header{Format: wav.FormatPCM, Channels: channels, SampleRate: rate, BitsPerSample: bits}
+	switch encoding {
+	case "linear":
+		// ok
+	case "mulaw":
+		h.Format, h.BitsPerSample = wav.FormatMuLaw, 8
+	case "alaw":
+		h.Format, h.BitsPerSample = wav.FormatALaw, 8
+	default:
+		return nil, fmt.Errorf("unknown encoding: %v. Must be linear, mulaw or alaw", encoding)
+	}
+	if !wav.Supported(h) || channels < 1 {
+		return nil, fmt.Errorf("%v channels, %v bits", channels, bits)
 	}
-	logw.Infof(ctx, "Done")
+	return h, nil
 }
 
-func process(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket, filename, output string, mono bool) error {
-	name := filepath.Base(filename)
+// parseChannels parses a comma-separated list of 1-based channels.
+func parseChannels(s string) ([]int, error) {
+	var ret []int
+	for _, str := range strings.Split(s, ",") {
+		c, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil || c < 1 {
+			return nil, fmt.Errorf("invalid channel: %q", str)
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
 
-	if mono {
-		// (a) If stereo, convert first to mono
+// newRunID returns a new unique run ID, such as "20170611-142512-3f9a". The
+// ID is valid as part of GCS bucket and object names.
+func newRunID() string {
+	return fmt.Sprintf("%v-%04x", time.Now().Format("20060102-150405"), rand.Intn(1<<16))
+}
+
+// readFiles reads a newline-delimited list of files. Blank lines are ignored.
+func readFiles(r io.Reader) ([]string, error) {
+	var ret []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// inputs fetches the input arguments as local files using the fetcher for their
+// scheme, except objects transcribed in place, such as GCS objects, which are
+// kept as URIs. Local glob patterns are expanded and local directories are
+// searched recursively for supported files, subject to the selection, and the
+// relative directories of the files are recorded in dirs, if not nil. Fetched
+// files are placed in a tmp directory, which is removed by the returned cleanup
+// function.
+func inputs(ctx context.Context, args []string, sel selection, dirs map[string]string) ([]string, func(), error) {
+	var dir string
+	cleanup := func() {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}
 
-		tmp := filepath.Join(os.TempDir(), name)
+	trees := 0
+	for _, arg := range args {
+		if fetch.Scheme(arg) == fetch.LocalScheme && isTree(strings.TrimPrefix(arg, fetch.LocalScheme+"://")) {
+			trees++
+		}
+	}
+
+	var ret []string
+	for _, arg := range args {
+		if fetch.IsInPlace(arg) {
+			// GCS objects are transcribed in place, if possible, without
+			// download or upload.
+			ret = append(ret, arg)
+			continue
+		}
+		if fetch.Scheme(arg) == fetch.LocalScheme {
+			name, err := fetch.Fetch(ctx, arg, "")
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			files, err := expand(name, trees > 1, sel, dirs)
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			ret = append(ret, files...)
+			continue
+		}
 
-		out, err := exec.Command("sox", filename, tmp, "remix", "1-2").CombinedOutput()
+		if dir == "" {
+			d, err := ioutil.TempDir("", "transcribe")
+			if err != nil {
+				return nil, nil, err
+			}
+			dir = d
+		}
+		file, err := fetch.Fetch(ctx, arg, dir)
 		if err != nil {
-			return fmt.Errorf("failed to convert %v to mono (err=%v): %v. Do you have sox installed?", name, err, string(out))
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to fetch %v: %v", arg, err)
 		}
-		defer os.Remove(tmp)
+		ret = append(ret, file)
+	}
+	return ret, cleanup, nil
+}
 
-		filename = tmp
+// audioLink returns a link to the source audio file for the given output file,
+// relative to the output directory if possible.
+func audioLink(source, output string) string {
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	if dir, err := filepath.Abs(filepath.Dir(output)); err == nil {
+		if rel, err := filepath.Rel(dir, abs); err == nil {
+			return filepath.ToSlash(rel)
+		}
 	}
+	return "file:///" + strings.TrimPrefix(filepath.ToSlash(abs), "/")
+}
 
-	// (b) Upload
+// isSupported returns true iff the file is in a supported format, i.e., wav,
+// flac or a format supported by a converter.
+func isSupported(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, f := range formats() {
+		if ext == f {
+			return true
+		}
+	}
+	return false
+}
 
-	object := path.Join("tmp/audio", strings.ToLower(name))
-	if err := storagex.UploadFile(cl, bucket, object, filename); err != nil {
-		return err
+// formats returns the supported input file extensions.
+func formats() []string {
+	ret := []string{".wav", ".flac", ".pcm", ".raw"}
+	for _, f := range audio.Formats() {
+		if f != ".wav" && f != ".flac" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// isPCM returns true iff the file is a headerless PCM file.
+func isPCM(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".pcm" || ext == ".raw"
+}
+
+// isWAV returns true iff the file is a wav file.
+func isWAV(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".wav"
+}
+
+// objectMetadata returns the metadata of staged objects for the given source
+// file: the custom metadata, run ID, source path and content hash.
+func objectMetadata(filename string, opts options) (map[string]string, error) {
+	hash, err := cache.Key(filename)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
 	}
-	defer storagex.TryDeleteObject(ctx, cl, bucket, object)
 
-	// (c) Transcribe
+	ret := map[string]string{}
+	for k, v := range opts.metadata {
+		ret[k] = v
+	}
+	ret["run"] = opts.run
+	ret["source-path"] = abs
+	ret["source-sha256"] = hash
+	return ret, nil
+}
 
-	before := time.Now()
+// needsConversion returns true iff the file must be converted before upload,
+// because of the options or the format is not supported by the API.
+func needsConversion(file string, opts audio.Options) bool {
+	if opts.Mono || opts.Normalize || len(opts.Channels) > 0 || opts.Track > 0 {
+		return true
+	}
+	if opts.SampleRate > 0 && opts.SampleRate != sampleRate(file) {
+		return true
+	}
+	if h, ok := readWAV(file); ok {
+		return !isNative(h)
+	}
+	return !isFLAC(file)
+}
 
-	phrases, err := transcribe.Submit(ctx, scl, bucket, object)
+// writeRaw writes the API response in JSON format to the given file.
+func writeRaw(filename string, resp proto.Message, opts options) error {
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(resp)
 	if err != nil {
 		return err
 	}
-	data := transcribe.PostProcess(phrases)
+	return writeFile(filename, data, opts)
+}
+
+// writeFile writes an output file under an advisory lock, so that parallel
+// workers on shared volumes do not produce interleaved files. The directory is
+// created, if it does not exist. If "-", the data is written to stdout.
+func writeFile(filename string, data []byte, opts options) error {
+	if filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	filename = pathx.Local(filename)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return filex.WriteFile(filename, data, 0644, !opts.norename)
+}
 
-	duration := time.Duration((time.Now().Sub(before).Nanoseconds() / 1e9) * 1e9)
-	logw.Infof(ctx, "Audio file %v contained %v text segments (%v letters). Time spent: %v", name, len(phrases), len(data), duration)
+// isNative returns true iff the wav format is supported natively by the API,
+// i.e., 16-bit PCM or 8-bit µ-law with a canonical header.
+func isNative(h *wav.Header) bool {
+	if len(h.Repairs) > 0 {
+		return false
+	}
+	return (h.Format == wav.FormatPCM && h.BitsPerSample == 16) || (h.Format == wav.FormatMuLaw && h.BitsPerSample == 8)
+}
 
-	// (d) Write output
+// readWAV returns the header of the file, if a wav file.
+func readWAV(file string) (*wav.Header, bool) {
+	if !isWAV(file) {
+		return nil, false
+	}
+	h, err := wav.ReadFile(file)
+	return h, err == nil
+}
 
-	if err := ioutil.WriteFile(output, []byte(data), 0644); err != nil {
-		return fmt.Errorf("failed to write output: %v", err)
+// channels returns the number of channels from the wav or flac header, if
+// known. It returns zero otherwise.
+func channels(file string) int {
+	if h, ok := readWAV(file); ok {
+		return h.Channels
+	}
+	if isFLAC(file) {
+		if h, err := flac.ReadFile(file); err == nil {
+			return h.Channels
+		}
+	}
+	return 0
+}
+
+// sampleRate returns the sample rate from the wav or flac header, if known. It
+// returns zero otherwise.
+func sampleRate(file string) int {
+	if h, ok := readWAV(file); ok {
+		return h.SampleRate
+	}
+	if isFLAC(file) {
+		if h, err := flac.ReadFile(file); err == nil {
+			return h.SampleRate
+		}
+	}
+	return 0
+}
+
+// targetRate returns the sample rate to convert the file to, if any. Unless
+// a rate is requested, only files at rates unsupported by the API are
+// resampled. It returns zero if the sample rate is kept.
+func targetRate(file string, opts options) int {
+	if opts.rate > 0 {
+		return opts.rate
+	}
+	if r := sampleRate(file); r != 0 && (r < transcribe.MinSampleRate || r > transcribe.MaxSampleRate) {
+		return defaultSampleRate
+	}
+	return 0
+}
+
+// isObject returns true iff the file is a GCS object URI, such as
+// "gs://bucket/foo.wav".
+func isObject(file string) bool {
+	return strings.HasPrefix(file, "gs://")
+}
+
+// isFLAC returns true iff the file is a flac file.
+func isFLAC(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".flac"
+}
+
+// run transcribes the files and returns the failures. It returns an error if
+// the GCP clients or temporary bucket cannot be set up. If the context is
+// cancelled, in-progress work is stopped, but temporary data is still removed.
+func run(ctx context.Context, files []string, opts options) ([]failure, []entry, error) {
+	// Cleanup must happen even if the context is cancelled.
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	// (2) Create GCP clients
+
+	cl := opts.storage
+	if cl == nil {
+		var err error
+		if cl, err = storagex.NewClient(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to create GCS client: %v", err)
+		}
+	}
+	scl, err := speech.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create speech client: %v", err)
+	}
+	defer scl.Close()
+
+	// (3) Create tmp location, if needed. GCS objects are not staged.
+
+	staged := false
+	for _, file := range files {
+		staged = staged || !isObject(file)
+	}
+	bucket := opts.bucket
+	if bucket == "" && staged {
+		bucket = fmt.Sprintf("transcribe-%v", opts.run)
+
+		err := retryx.Do(ctx, opts.retry, func() error {
+			return storagex.NewBucket(ctx, cl, *project, bucket)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create tmp bucket %v: %v", bucket, err)
+		}
+		defer storagex.TryDeleteBucket(cleanupCtx, cl, bucket)
+
+		infof(ctx, "Using temporary GCS bucket '%v'", bucket)
+	}
+
+	// The remaining time of the batch is predicted from the observed throughput
+	// and the probed audio durations.
+
+	var audio time.Duration
+	for _, file := range files {
+		audio += opts.durations[file]
+	}
+
+	tp := newThroughput(len(files), audio)
+	opts.bars = newBars(tp)
+	defer opts.bars.stop()
+
+	if *merge {
+		// (4) Upload and transcribe the parts in parallel and process them as a
+		// single recording.
+
+		name := filepath.Base(files[0])
+		mopts := opts
+		mopts.subdir = opts.dirs[files[0]]
+
+		infof(ctx, "Transcribing %v audio files as parts of %v ...", len(files), name)
+
+		e := newEntry(files[0], opts.outputs[files[0]], statusDone, mopts)
+		e.Parts = files
+		e.Duration = audio.Seconds()
+
+		start := time.Now()
+		n, err := processMerged(ctx, scl, cl, bucket, files, opts.outputs[files[0]], mopts)
+		e.finish(start, n, err)
+		tp.add(audio, err)
+		opts.bars.finish()
+		if err != nil {
+			errorf(ctx, "Failed to process %v: %v", name, err)
+			return []failure{newFailure(name, err)}, []entry{e}, nil
+		}
+
+		infof(ctx, "Transcribed %v", name)
+		return nil, []entry{e}, nil
+	}
+
+	infof(ctx, "Transcribing %v audio files in parallel", len(files))
+
+	// (4) Upload, transcribe and process the files in parallel
+
+	var failures []failure
+	var entries []entry
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	for _, name := range files {
+		wg.Add(1)
+		go func(filename string) {
+			defer wg.Done()
+
+			name := filepath.Base(filename)
+			out := opts.outputs[filename]
+			fopts := withJob(opts, filename)
+			fopts.subdir = opts.dirs[filename]
+
+			infof(ctx, "Transcribing %v ...", name)
+
+			e := newEntry(filename, out, statusDone, fopts)
+			e.Duration = opts.durations[filename].Seconds()
+
+			start := time.Now()
+			n, err := process(ctx, scl, cl, bucket, filename, out, fopts)
+			e.finish(start, n, err)
+			tp.add(opts.durations[filename], err)
+			opts.bars.finish()
+
+			mu.Lock()
+			entries = append(entries, e)
+			mu.Unlock()
+
+			if err != nil {
+				errorf(ctx, "Failed to process %v: %v. %v", name, err, tp)
+
+				mu.Lock()
+				failures = append(failures, newFailure(name, err))
+				mu.Unlock()
+				return
+			}
+
+			infof(ctx, "Transcribed %v. %v", name, tp)
+		}(name)
+	}
+	wg.Wait()
+
+	return failures, entries, nil
+}
+
+// process transcribes the audio file and writes the output. It returns the
+// number of phrases.
+func process(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket, filename, output string, opts options) (int, error) {
+	before := time.Now()
+
+	phrases, err := recognize(ctx, scl, cl, bucket, filename, opts)
+	if err != nil {
+		return 0, err
+	}
+	source := filename
+	if isObject(filename) {
+		source = "" // not local
+	}
+	return len(phrases), publish(ctx, filepath.Base(filename), source, output, phrases, before, opts)
+}
+
+// processMerged transcribes the files as sequential parts of one recording,
+// such as a recording split by the recorder, and writes a single transcript
+// with offsets relative to the whole recording. It returns the number of
+// phrases.
+func processMerged(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket string, files []string, output string, opts options) (int, error) {
+	before := time.Now()
+
+	offsets := make([]time.Duration, len(files))
+	var total time.Duration
+	for i, file := range files {
+		d := opts.durations[file]
+		if d == 0 {
+			return 0, fmt.Errorf("unknown duration of part %v", filepath.Base(file))
+		}
+		offsets[i] = total
+		total += d
+	}
+
+	// The remaining parts are canceled if any part fails, as the recording
+	// cannot be published without it.
+
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]transcribe.Phrase, len(files))
+	var failed error // first failure
+	var once sync.Once
+
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			phrases, err := recognize(rctx, scl, cl, bucket, files[i], opts)
+			if err != nil {
+				once.Do(func() {
+					failed = fmt.Errorf("part %v: %w", filepath.Base(files[i]), err)
+					cancel()
+				})
+				return
+			}
+			results[i] = transcribe.Remap(phrases, func(d time.Duration) time.Duration {
+				return offsets[i] + d
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return 0, failed
+	}
+	var phrases []transcribe.Phrase
+	for i := range files {
+		phrases = append(phrases, results[i]...)
+	}
+	return len(phrases), publish(ctx, filepath.Base(files[0]), "", output, phrases, before, opts)
+}
+
+// recognize converts, if needed, and transcribes the audio file. Offsets are
+// relative to the original audio.
+func recognize(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket, filename string, opts options) ([]transcribe.Phrase, error) {
+	if isObject(filename) {
+		return transcribeObject(ctx, scl, cl, filename, opts)
+	}
+
+	name := filepath.Base(filename)
+
+	// Staged objects are self-describing, in case they are retained.
+
+	meta, err := objectMetadata(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// (a) If needed, transcode to wav or flac and/or convert stereo to mono
+
+	if isPCM(filename) {
+		if opts.pcm == nil {
+			return nil, fmt.Errorf("%w: %v is headerless PCM. Use --pcm-rate to provide the format", transcribe.ErrBadAudioFormat, name)
+		}
+		wrapped, cleanup, err := wrap(ctx, filename, opts.pcm, opts.cache)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		filename = wrapped
+	}
+
+	telephony := false
+	if h, ok := readWAV(filename); ok {
+		if len(h.Repairs) > 0 {
+			infof(ctx, "Repairing wav header of %v: %v", name, strings.Join(h.Repairs, ", "))
+		}
+		telephony = h.Format == wav.FormatMuLaw || h.Format == wav.FormatALaw
+	}
+
+	// Stereo wav and flac files are downmixed automatically, unless channels are
+	// recognized separately. The channel layout of other formats is not known
+	// before transcoding, so --mono forces it.
+	mono := opts.mono || (channels(filename) > 1 && !opts.separate)
+
+	aopts := audio.Options{Mono: mono, Normalize: opts.loudnorm, SampleRate: targetRate(filename, opts), Channels: opts.selected, Track: opts.track}
+	if needsConversion(filename, aopts) {
+		// Use the requested converter, if it can convert the file. Otherwise, use
+		// the first that can, preferring in-process conversion of wav.
+
+		conv := opts.converter
+		if conv == nil || conv.Probe(filename, aopts) != nil {
+			c, err := audio.Find(filename, aopts)
+			if err != nil {
+				return nil, err
+			}
+			conv = c
+		}
+
+		converted, cleanup, err := transcode(ctx, conv, filename, aopts, opts.cache)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		filename = converted
+	}
+
+	var segments []wav.Segment // nil if not trimmed
+	if opts.silence.MinDuration > 0 {
+		if h, ok := readWAV(filename); ok && wav.Supported(h) {
+			// Remove long silences to reduce billed audio. Offsets are mapped back
+			// to the original audio after transcription.
+
+			trimmed, list, cleanup, err := trim(ctx, filename, opts.silence, opts.cache)
+			if err != nil {
+				return nil, err
+			}
+			defer cleanup()
+
+			if t, err := wav.ReadFile(trimmed); err == nil {
+				infof(ctx, "Trimmed %v of silence from %v", h.Duration()-t.Duration(), name)
+			}
+			filename = trimmed
+			segments = list
+		} else {
+			infof(ctx, "Not trimming silence from %v: not a supported wav file", name)
+		}
+	}
+
+	// (b) Transcribe, in chunks if long
+
+	var phrases []transcribe.Phrase
+	if h, ok := readWAV(filename); ok && opts.chunk > 0 && h.Duration() > opts.chunk && wav.Supported(h) {
+		// Split long recordings at silences into chunks, which are transcribed
+		// in parallel rather than as a single long-running operation.
+
+		var chunks []wav.Segment
+		chunks, err = wav.Chunks(filename, opts.chunk, opts.silence.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split %v: %v", name, err)
+		}
+		infof(ctx, "Splitting %v into %v chunks", name, len(chunks))
+
+		phrases, err = transcribeChunks(ctx, scl, cl, bucket, name, filename, chunks, telephony, meta, opts)
+	} else {
+		phrases, err = transcribeFile(ctx, scl, cl, bucket, name, filename, telephony, meta, opts)
+	}
+	if err != nil {
+		if errors.Is(err, transcribe.ErrBadChannels) && !mono && !opts.separate {
+			return nil, fmt.Errorf("%w. Is the file stereo? If so, use --mono", err)
+		}
+		return nil, err
+	}
+
+	if segments != nil {
+		phrases = transcribe.Remap(phrases, func(d time.Duration) time.Duration {
+			return wav.Offset(segments, d)
+		})
+	}
+	return phrases, nil
+}
+
+// publish post-processes the phrases of the source audio file and writes the
+// output. If the source is empty, such as for merged files, outputs that refer
+// to the audio file are omitted.
+func publish(ctx context.Context, name, source, output string, phrases []transcribe.Phrase, before time.Time, opts options) error {
+	// (c) Post-process
+
+	if len(opts.redact) > 0 {
+		var list []transcribe.Redaction
+		phrases, list = transcribe.Redact(phrases, opts.redact)
+
+		if opts.edl {
+			if err := writeEDL(strings.TrimSuffix(output, filepath.Ext(output))+".edl", list, opts); err != nil {
+				return err
+			}
+		}
+	}
+	redacted := phrases
+
+	paragraphs := transcribe.PostProcess
+	if opts.pause > 0 {
+		phrases = transcribe.Turns(phrases, opts.pause)
+		paragraphs = transcribe.FormatTurns
+	} else if opts.gap > 0 || transcribe.Diarized(phrases) {
+		phrases = transcribe.Turns(phrases, opts.gap)
+		paragraphs = transcribe.FormatDialogue
+	}
+	if opts.low > 0 {
+		phrases = transcribe.MarkLowConfidence(phrases, opts.low)
+	}
+	if opts.showconf {
+		phrases = transcribe.AnnotateConfidence(phrases)
+	}
+	render := func(phrases []transcribe.Phrase) string {
+		if len(opts.markers) > 0 {
+			return transcribe.FormatSections(transcribe.Split(phrases, opts.markers), paragraphs)
+		}
+		return paragraphs(phrases)
+	}
+	result := format.Result{
+		Run:        opts.run,
+		File:       name,
+		Phrases:    phrases,
+		Structured: redacted,
+		Text:       render(phrases),
+		Options:    opts.layout,
+	}
+	if opts.player && source != "" {
+		result.Audio = audioLink(source, output)
+	}
+	data, err := opts.format.Format(result)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %v", err)
+	}
+
+	spent := time.Duration((time.Now().Sub(before).Nanoseconds() / 1e9) * 1e9)
+	infof(ctx, "Audio file %v contained %v text segments (%v letters). Time spent: %v", name, len(phrases), len(data), spent)
+
+	// (d) Write output. The main output is written last, because its presence
+	// marks the file as transcribed.
+
+	if opts.words {
+		if err := writeWords(strings.TrimSuffix(output, filepath.Ext(output))+".words.json", name, redacted, opts); err != nil {
+			return err
+		}
+	}
+	if opts.speakers {
+		if err := writeSpeakers(ctx, output, phrases, render, opts); err != nil {
+			return fmt.Errorf("failed to write speaker output: %v", err)
+		}
+	}
+	if opts.chapters {
+		if err := writeChapters(ctx, source, output, phrases, render, opts); err != nil {
+			errorf(ctx, "Failed to write chapters of %v: %v", name, err)
+		}
+	}
+	if err := writeFile(output, encode(data, opts), opts); err != nil {
+		return fmt.Errorf("failed to write output: %v", err)
+	}
+
+	// (e) Store in sinks, if any
+
+	if opts.sink != nil {
+		if err := opts.sink.Write(ctx, sink.Record{Run: opts.run, File: name, Phrases: redacted}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transcribeChunks transcribes the given segments of the wav file in parallel
+// and stitches the phrases back together in order, with offsets relative to the
+// whole file. If a chunk fails, the remaining chunks are cancelled.
+func transcribeChunks(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket, name, filename string, chunks []wav.Segment, telephony bool, meta map[string]string, opts options) ([]transcribe.Phrase, error) {
+	files, cleanup, err := split(ctx, filename, chunks)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]transcribe.Phrase, len(chunks))
+	var failed error // first failure
+	var once sync.Once
+
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			m := map[string]string{"chunk": fmt.Sprintf("%v/%v", i+1, len(chunks))}
+			for k, v := range meta {
+				m[k] = v
+			}
+
+			part := fmt.Sprintf("%v.part%v", name, i+1)
+			phrases, err := transcribeFile(ctx, scl, cl, bucket, part, files[i], telephony, m, opts)
+			if err != nil {
+				once.Do(func() {
+					failed = fmt.Errorf("chunk %v of %v: %w", i+1, len(chunks), err)
+					cancel()
+				})
+				return
+			}
+			results[i] = transcribe.Remap(phrases, func(d time.Duration) time.Duration {
+				return chunks[i].Start + d
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	var ret []transcribe.Phrase
+	for i := range chunks {
+		ret = append(ret, results[i]...)
+	}
+	return ret, nil
+}
+
+// transcribeFile uploads and transcribes a single wav or flac file. The staged
+// object has the given metadata.
+func transcribeFile(ctx context.Context, scl *speech.Client, cl *storage.Service, bucket, name, filename string, telephony bool, meta map[string]string, opts options) ([]transcribe.Phrase, error) {
+	if opts.flac && isWAV(filename) {
+		// Compress wav to flac, which roughly halves the upload size.
+
+		compressed, cleanup, err := compress(ctx, filename, opts.cache)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		filename = compressed
+	}
+
+	// (a) Inspect format
+
+	topts := transcribe.Options{
+		Config:   opts.config,
+		Retry:    opts.retry,
+		Separate: opts.separate,
+	}
+	var duration time.Duration
+	if isFLAC(filename) {
+		// FLAC is supported natively. The API reads the format from the header.
+
+		h, err := flac.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %v: %v", name, err)
+		}
+		if h.SampleRate < transcribe.MinSampleRate || h.SampleRate > transcribe.MaxSampleRate {
+			return nil, fmt.Errorf("%w: %v has unsupported sample rate %vHz. Must be %v-%vHz", transcribe.ErrBadAudioFormat, name, h.SampleRate, transcribe.MinSampleRate, transcribe.MaxSampleRate)
+		}
+
+		topts.Encoding = transcribe.FLAC
+		topts.Channels = h.Channels
+		duration = h.Duration()
+	} else {
+		h, err := wav.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %v: %v", name, err)
+		}
+		if !isNative(h) {
+			return nil, fmt.Errorf("%w: %v is %v. Must be 16-bit PCM or µ-law", transcribe.ErrBadAudioFormat, name, h)
+		}
+		if h.Format == wav.FormatMuLaw {
+			topts.Encoding = transcribe.MULAW
+		}
+		if h.SampleRate < transcribe.MinSampleRate || h.SampleRate > transcribe.MaxSampleRate {
+			return nil, fmt.Errorf("%w: %v has unsupported sample rate %vHz. Must be %v-%vHz", transcribe.ErrBadAudioFormat, name, h.SampleRate, transcribe.MinSampleRate, transcribe.MaxSampleRate)
+		}
+
+		topts.SampleRate = h.SampleRate
+		topts.Channels = h.Channels
+		duration = h.Duration()
+	}
+	if telephony && (opts.config == nil || opts.config.Model == "") {
+		topts.Model = transcribe.PhoneCallModel
+	}
+	timeout := scale(duration, &topts, opts)
+
+	var key string
+	if opts.responses {
+		// Transcriptions are cached by audio content and settings. If cached, the
+		// audio is not uploaded.
+
+		k, err := cache.Key(filename, transcribe.Settings(topts))
+		if err != nil {
+			return nil, err
+		}
+		if phrases, ok := cachedResponse(ctx, name, k, opts); ok {
+			return phrases, nil
+		}
+		key = k
+	}
+
+	// (b) Upload
+
+	object := path.Join("tmp/audio", opts.run, strings.ToLower(name))
+	debugf(ctx, "Uploading %v to gs://%v/%v with metadata %v", filename, bucket, object, meta)
+	var uploaded func(sent, size int64)
+	if opts.bars != nil {
+		uploaded = func(sent, size int64) {
+			opts.bars.update(name, "uploading", int(100*sent/max(size, 1)))
+		}
+	}
+	err := retryx.Do(ctx, opts.retry, func() error {
+		return storagex.UploadFile(ctx, cl, bucket, object, filename, meta, uploaded)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer storagex.TryDeleteObject(context.WithoutCancel(ctx), cl, bucket, object)
+
+	// (c) Transcribe
+
+	return submit(ctx, scl, name, transcribe.GCS(bucket, object), topts, timeout, key, opts)
+}
+
+// transcribeObject transcribes the wav or flac object at the given GCS URI in
+// place. The object is neither downloaded nor deleted. The sample rate is read
+// from the header by the API, but the number of channels must be given.
+func transcribeObject(ctx context.Context, scl *speech.Client, cl *storage.Service, uri string, opts options) ([]transcribe.Phrase, error) {
+	bucket, object, err := storagex.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(object)
+
+	duration, channels, err := readObject(ctx, cl, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %v", name, err)
+	}
+
+	topts := transcribe.Options{
+		Config:   opts.config,
+		Retry:    opts.retry,
+		Separate: opts.separate,
+		Channels: channels,
+	}
+	if isFLAC(uri) {
+		topts.Encoding = transcribe.FLAC
+	}
+	timeout := scale(duration, &topts, opts)
+
+	var key string
+	if opts.responses {
+		obj, err := storagex.Stat(ctx, cl, bucket, object)
+		if err != nil {
+			return nil, err
+		}
+		key = cache.KeyOf(storagex.ContentHash(obj), transcribe.Settings(topts))
+		if phrases, ok := cachedResponse(ctx, name, key, opts); ok {
+			return phrases, nil
+		}
+	}
+	return submit(ctx, scl, name, transcribe.GCS(bucket, object), topts, timeout, key, opts)
+}
+
+// scale returns the timeout of transcribing audio of the given duration and
+// scales the retry backoff to it, so that long recordings are not subject to
+// limits tuned for short ones. The timeout is zero if the duration is unknown.
+func scale(duration time.Duration, topts *transcribe.Options, opts options) time.Duration {
+	if duration == 0 {
+		return 0
+	}
+	topts.Retry = opts.retry.Scale(float64(duration) / float64(10*time.Minute))
+	return time.Duration(opts.factor*float64(duration)) + opts.margin
+}
+
+// submit transcribes the source audio with progress logging and archiving of
+// the raw response, if requested, within the operation limit, if any. The
+// timeout, if not zero, applies once an operation slot is acquired, so that
+// queued files do not time out. If the key is not empty, the response is
+// cached under it.
+func submit(ctx context.Context, scl *speech.Client, name string, src transcribe.Source, topts transcribe.Options, timeout time.Duration, key string, opts options) ([]transcribe.Phrase, error) {
+	defer opts.bars.remove(name)
+
+	if opts.ops != nil {
+		// Wait for an operation slot to stay within the project quota.
+
+		opts.bars.update(name, "queued", 0)
+		select {
+		case opts.ops <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-opts.ops }()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var last time.Time
+	progress := func(p transcribe.Progress) {
+		debugf(ctx, "Operation of %v: %v%% (last update %v)", name, p.Percent, p.LastUpdate.Format(time.RFC3339))
+		if opts.bars != nil {
+			opts.bars.update(name, "transcribing", p.Percent)
+			return
+		}
+		if time.Since(last) < opts.interval {
+			return // rate-limited
+		}
+		infof(ctx, "Transcribing %v: %v%% (started %v, last update %v)", name, p.Percent, p.Start.Local().Format(time.Kitchen), p.LastUpdate.Local().Format(time.Kitchen))
+		last = time.Now()
+	}
+
+	topts.Progress = progress
+	topts.Raw = func(resp proto.Message) {
+		dumpResponse(ctx, name, resp, opts)
+		if key == "" {
+			return
+		}
+		data, err := proto.Marshal(resp)
+		if err == nil {
+			err = opts.cache.Put(key, responseExt, data)
+		}
+		if err != nil {
+			errorf(ctx, "Failed to cache response of %v: %v", name, err)
+		}
+	}
+
+	debugf(ctx, "Transcribing %v from %v: encoding %v, sample rate %v, channels %v, model %q, config %v", name, src, topts.Encoding, topts.SampleRate, topts.Channels, topts.Model, topts.Config)
+	return transcribe.Submit(ctx, scl, src, topts)
+}
+
+// responseExt is the extension of cached API responses.
+const responseExt = ".response.pb"
+
+// cachedResponse returns the phrases of the cached API response with the given
+// key, if any. The response is archived as if transcribed.
+func cachedResponse(ctx context.Context, name, key string, opts options) ([]transcribe.Phrase, bool) {
+	filename, ok := opts.cache.Lookup(key, responseExt)
+	if !ok {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	resp := &speechpb.LongRunningRecognizeResponse{}
+	if err := proto.Unmarshal(data, resp); err != nil {
+		errorf(ctx, "Ignoring invalid cached response of %v: %v", name, err)
+		return nil, false
+	}
+
+	infof(ctx, "Using cached response for %v", name)
+	dumpResponse(ctx, name, resp, opts)
+	return transcribe.Decode(resp), true
+}
+
+// dumpResponse writes the API response as JSON to the raw response directory,
+// if any, such as 'raw/foo.wav.response.json'.
+func dumpResponse(ctx context.Context, name string, resp proto.Message, opts options) {
+	if opts.raw == "" {
+		return
+	}
+	filename := filepath.Join(opts.raw, opts.subdir, name+".response.json")
+	if err := writeRaw(filename, resp, opts); err != nil {
+		errorf(ctx, "Failed to write raw response of %v: %v", name, err)
+	}
+}
+
+// writeSpeakers writes a transcript per speaker, if diarization is enabled, such
+// as 'foo.wav.speaker1.txt'. The phrases of each speaker are formatted with the
+// given function.
+func writeSpeakers(ctx context.Context, output string, phrases []transcribe.Phrase, render func([]transcribe.Phrase) string, opts options) error {
+	m := transcribe.BySpeaker(phrases)
+	for speaker, list := range m {
+		filename := fmt.Sprintf("%v.speaker%v.txt", strings.TrimSuffix(output, filepath.Ext(output)), speaker)
+		if err := writeFile(filename, encode([]byte(render(list)), opts), opts); err != nil {
+			return err
+		}
+	}
+	if len(m) > 0 {
+		infof(ctx, "Wrote transcripts of %v speakers of %v", len(m), filepath.Base(output))
+	}
+	return nil
+}
+
+// writeWords writes the words of the phrases as JSON, such as
+// 'foo.wav.words.json'.
+func writeWords(filename, name string, phrases []transcribe.Phrase, opts options) error {
+	var buf bytes.Buffer
+	if err := transcribe.WriteWords(&buf, opts.run, name, phrases); err != nil {
+		return fmt.Errorf("failed to write words: %v", err)
+	}
+	if err := writeFile(filename, buf.Bytes(), opts); err != nil {
+		return fmt.Errorf("failed to write words: %v", err)
+	}
+	return nil
+}
+
+func writeEDL(filename string, list []transcribe.Redaction, opts options) error {
+	var buf bytes.Buffer
+	if err := transcribe.WriteEDL(&buf, list); err != nil {
+		return fmt.Errorf("failed to write redaction list: %v", err)
+	}
+	if err := writeFile(filename, buf.Bytes(), opts); err != nil {
+		return fmt.Errorf("failed to write redaction list: %v", err)
 	}
 	return nil
 }
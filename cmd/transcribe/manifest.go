@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/herohde/transcribe/pkg/fetch"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// job is an input of a manifest with per-file options, which take precedence
+// over the options given by flags.
+type job struct {
+	File     string   `json:"file"`
+	Language string   `json:"language,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	Output   string   `json:"output,omitempty"` // output file name
+	Hints    []string `json:"hints,omitempty"`
+}
+
+// readManifest reads the jobs of a manifest in CSV format, with a header row
+// naming the columns file, language, model, output and hints (separated by
+// semicolons), or JSON format, as a list of jobs. Relative files are resolved
+// against the directory of the manifest.
+func readManifest(filename string) ([]job, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var ret []job
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		if err := json.NewDecoder(fd).Decode(&ret); err != nil {
+			return nil, fmt.Errorf("invalid manifest %v: %v", filename, err)
+		}
+	} else {
+		ret, err = readCSVManifest(fd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest %v: %v", filename, err)
+		}
+	}
+
+	for i, j := range ret {
+		if j.File == "" {
+			return nil, fmt.Errorf("invalid manifest %v: job %v has no file", filename, i+1)
+		}
+		if j.Output != "" {
+			if err := checkOutputName(j.Output); err != nil {
+				return nil, fmt.Errorf("invalid manifest %v: job %v: %v", filename, i+1, err)
+			}
+		}
+		if fetch.Scheme(j.File) == fetch.LocalScheme && !strings.Contains(j.File, "://") && !filepath.IsAbs(j.File) {
+			ret[i].File = filepath.Join(filepath.Dir(filename), j.File)
+		}
+	}
+	return ret, nil
+}
+
+func readCSVManifest(r io.Reader) ([]job, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["file"]; !ok {
+		return nil, fmt.Errorf("no file column")
+	}
+	get := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var ret []job
+	for _, record := range records[1:] {
+		j := job{
+			File:     get(record, "file"),
+			Language: get(record, "language"),
+			Model:    get(record, "model"),
+			Output:   get(record, "output"),
+		}
+		if hints := get(record, "hints"); hints != "" {
+			j.Hints = strings.Split(hints, ";")
+		}
+		ret = append(ret, j)
+	}
+	return ret, nil
+}
+
+// manifestInputs fetches the files of the manifest jobs as local files, like
+// inputs, and records the job of each file in jobs.
+func manifestInputs(ctx context.Context, filename string, sel selection, dirs map[string]string, jobs map[string]job) ([]string, func(), error) {
+	list, err := readManifest(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cleanups []func()
+	cleanup := func() {
+		for _, fn := range cleanups {
+			fn()
+		}
+	}
+
+	var ret []string
+	for _, j := range list {
+		files, fn, err := inputs(ctx, []string{j.File}, sel, dirs)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, fn)
+		if j.Output != "" && len(files) > 1 {
+			cleanup()
+			return nil, nil, fmt.Errorf("output %v of %v is given for %v files: use a row per file", j.Output, j.File, len(files))
+		}
+
+		for _, file := range files {
+			jobs[file] = j
+		}
+		ret = append(ret, files...)
+	}
+	return ret, cleanup, nil
+}
+
+// withJob returns the options with the per-file options of the job of the
+// given file, if any.
+func withJob(opts options, file string) options {
+	j, ok := opts.jobs[file]
+	if !ok || (j.Language == "" && j.Model == "" && len(j.Hints) == 0) {
+		return opts
+	}
+
+	config := &speechpb.RecognitionConfig{}
+	if opts.config != nil {
+		config = proto.Clone(opts.config).(*speechpb.RecognitionConfig)
+	}
+	if j.Language != "" {
+		config.LanguageCode = j.Language
+	}
+	if j.Model != "" {
+		config.Model = j.Model
+	}
+	if len(j.Hints) > 0 {
+		config.SpeechContexts = append(config.SpeechContexts, &speechpb.SpeechContext{Phrases: j.Hints})
+	}
+	opts.config = config
+	return opts
+}
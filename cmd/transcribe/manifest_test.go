@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []job
+	}{
+		{
+			"jobs.csv",
+			"File,Language,output,hints\na.wav,de-DE,a.txt,foo;bar baz\n/abs/b.wav,,,\n",
+			[]job{
+				{File: filepath.Join(dir, "a.wav"), Language: "de-DE", Output: "a.txt", Hints: []string{"foo", "bar baz"}},
+				{File: "/abs/b.wav"},
+			},
+		},
+		{
+			"jobs.json",
+			`[{"file": "a.wav", "model": "video"}, {"file": "gs://bucket/b.wav", "output": "b.txt"}]`,
+			[]job{
+				{File: filepath.Join(dir, "a.wav"), Model: "video"},
+				{File: "gs://bucket/b.wav", Output: "b.txt"},
+			},
+		},
+		{"empty.csv", "", nil},
+		{"nofile.csv", "language\nen-US\n", nil},
+		{"missing.json", `[{"language": "en-US"}]`, nil},
+		{"dir.csv", "file,output\na.wav,out/a.txt\n", nil},
+		{"dot.csv", "file,output\na.wav,.\n", nil},
+		{"dotdot.json", `[{"file": "a.wav", "output": ".."}]`, nil},
+		{"backslash.csv", "file,output\na.wav,a\\b.txt\n", nil},
+		{"invalid.json", `{"file": "a.wav"}`, nil},
+	}
+
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := readManifest(filename)
+		if tt.expected == nil && tt.name != "empty.csv" {
+			if err == nil {
+				t.Errorf("readManifest(%v) = %v, want error", tt.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readManifest(%v) failed: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("readManifest(%v) = %+v, want %+v", tt.name, actual, tt.expected)
+		}
+	}
+}
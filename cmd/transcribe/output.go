@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+// outputFields are the fields available to output name templates.
+type outputFields struct {
+	Name     string // audio file name, such as "foo.wav"
+	Basename string // audio file name without extension, such as "foo"
+	Ext      string // audio file extension without dot, such as "wav"
+	Format   string // output file extension without dot, such as "txt"
+	Lang     string // language code, such as "en-US"
+	Engine   string // speech recognition engine, such as "google"
+	Date     string // date of the run, such as "2017-06-11"
+	Run      string // run ID
+}
+
+// engine is the speech recognition engine.
+const engine = "google"
+
+// defaultLang is the language code used in output names, if not configured.
+const defaultLang = "en-US"
+
+// parseOutputTemplate parses the output name template for use by outputName.
+// It fails if the template produces no valid file name with the options.
+func parseOutputTemplate(text string, opts options) (*template.Template, error) {
+	tmpl, err := template.New("out").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, newOutputFields("foo.wav", opts)); err != nil {
+		return nil, err
+	}
+	if err := checkOutputName(sb.String()); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// checkOutputName returns an error if the name is not a plain file name, such
+// as if empty or with directories. Otherwise invalid characters are replaced by
+// outputName.
+func checkOutputName(name string) error {
+	if strings.TrimSpace(name) == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid output name %q: must be a file name without directories", name)
+	}
+	return nil
+}
+
+// outputName returns the name of the output file for the given file in the
+// output format, such as 'foo.wav.txt' or as given by the output template or
+// manifest.
+func outputName(file string, opts options) (string, error) {
+	if j, ok := opts.jobs[file]; ok && j.Output != "" {
+		return pathx.SafeName(j.Output), nil
+	}
+	fields := newOutputFields(file, opts)
+	if opts.template != nil {
+		var sb strings.Builder
+		if err := opts.template.Execute(&sb, fields); err != nil {
+			return "", fmt.Errorf("invalid output name for %v: %v", file, err)
+		}
+		return pathx.SafeName(sb.String()), nil
+	}
+	return pathx.SafeName(fields.Name + "." + fields.Format), nil
+}
+
+func newOutputFields(file string, opts options) outputFields {
+	ext := opts.format.Ext()
+	lang := fileLang(file, opts)
+
+	name := filepath.Base(file)
+	return outputFields{
+		Name:     name,
+		Basename: strings.TrimSuffix(name, filepath.Ext(name)),
+		Ext:      strings.TrimPrefix(filepath.Ext(name), "."),
+		Format:   strings.TrimPrefix(ext, "."),
+		Lang:     lang,
+		Engine:   engine,
+		Date:     opts.date.Format("2006-01-02"),
+		Run:      opts.run,
+	}
+}
+
+// fileLang returns the language code of the given file used in output names.
+func fileLang(file string, opts options) string {
+	if j, ok := opts.jobs[file]; ok && j.Language != "" {
+		return j.Language
+	}
+	if opts.config != nil && opts.config.LanguageCode != "" {
+		return opts.config.LanguageCode
+	}
+	return defaultLang
+}
+
+// outputPath returns the path of the output file for the given file in the
+// output directory, or "-" if written to stdout. Files found in directory
+// arguments are written to the same relative directory under the output
+// directory, so that same-named files do not collide. If versioned, the first
+// unused versioned path is returned.
+func outputPath(file string, opts options) (string, error) {
+	if opts.out == "-" {
+		return "-", nil
+	}
+	name, err := outputName(file, opts)
+	if err != nil {
+		return "", err
+	}
+	ret := filepath.Join(opts.out, opts.dirs[file], name)
+	if opts.suffix {
+		return versioned(ret), nil
+	}
+	return ret, nil
+}
+
+// outputPaths returns the paths of the output files for the given files in the
+// output directory. It fails if two files have the same output, such as if the
+// output template uses neither Name nor Basename, unless written to stdout.
+func outputPaths(files []string, opts options) (map[string]string, error) {
+	ret := map[string]string{}
+	seen := map[string]string{}
+	for _, file := range files {
+		out, err := outputPath(file, opts)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[out]; ok && out != "-" {
+			return nil, fmt.Errorf("%v and %v have the same output %v. Use --out-template with {{.Name}} or {{.Basename}}", prev, file, out)
+		}
+		seen[out] = file
+		ret[file] = out
+	}
+	return ret, nil
+}
+
+// versioned returns the path, if it does not exist, or otherwise the first path
+// with a version suffix that does not exist, such as 'foo.wav.v2.txt'.
+func versioned(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%v.v%v%v", base, i, ext)
+	}
+}
+
+// expand returns the local files of the argument: the file itself, the files
+// matching a glob pattern or the supported files in a directory tree or matching
+// a recursive pattern, such as "recordings/**/*.wav". Files found by patterns or
+// in directories are filtered by the selection. If nested, files in directory
+// trees are mirrored under the name of the directory, so that the files of
+// several directory arguments do not collide. Their relative directories are
+// recorded in dirs, if not nil.
+func expand(name string, nested bool, sel selection, dirs map[string]string) ([]string, error) {
+	if fi, err := os.Stat(name); err == nil {
+		if fi.IsDir() {
+			return walk(name, nil, nested, sel, dirs)
+		}
+		return []string{name}, nil
+	}
+
+	if pathx.IsRecursive(name) {
+		files, err := walk(pathx.Root(name), func(path string) bool {
+			ok, _ := pathx.Match(name, path)
+			return ok
+		}, nested, sel, dirs)
+		if err == nil && len(files) == 0 {
+			return nil, fmt.Errorf("no files match %v", name)
+		}
+		return files, err
+	}
+
+	files, err := pathx.Expand([]string{name})
+	if err != nil || !strings.ContainsAny(name, "*?[") {
+		return files, err
+	}
+	var ret []string
+	for _, file := range files {
+		if sel.selected(pathx.Root(name), file) {
+			ret = append(ret, file)
+		}
+	}
+	return ret, nil
+}
+
+// walk returns the supported files in the directory tree accepted by the match
+// function, if any, and the selection. It records their relative directories,
+// prefixed by the name of the root if nested, in dirs, if not nil.
+func walk(root string, match func(path string) bool, nested bool, sel selection, dirs map[string]string) ([]string, error) {
+	var prefix string
+	if nested {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		prefix = filepath.Base(abs)
+	}
+
+	var ret []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isSupported(path) || (match != nil && !match(path)) || !sel.selected(root, path) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		rel = filepath.Join(prefix, rel)
+		if rel != "." && dirs != nil {
+			dirs[path] = rel
+		}
+		ret = append(ret, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %v: %v", root, err)
+	}
+	return ret, nil
+}
+
+// isTree returns true iff the argument is a local directory or recursive
+// pattern, i.e., its files are mirrored under --out.
+func isTree(name string) bool {
+	if fi, err := os.Stat(name); err == nil {
+		return fi.IsDir()
+	}
+	return pathx.IsRecursive(name)
+}
+
+// selection selects the files found in directories or by patterns, such as
+// per --include and --exclude.
+type selection struct {
+	include, exclude string // comma-separated patterns, if any
+}
+
+// selected returns true iff the file found under the given root is included
+// and not excluded.
+func (s selection) selected(root, path string) bool {
+	if s.include != "" && !matchAny(s.include, root, path) {
+		return false
+	}
+	return s.exclude == "" || !matchAny(s.exclude, root, path)
+}
+
+// matchAny returns true iff the path matches any of the comma-separated
+// patterns. Patterns with a path separator, such as "drafts/**", are matched
+// against the path relative to the root, such as the searched directory, and
+// others against the name.
+func matchAny(patterns, root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+
+		target := filepath.Base(path)
+		if strings.ContainsAny(p, `/\`) {
+			target = rel
+		}
+		if ok, _ := pathx.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encoded are the output formats to which the encoding options apply. Others,
+// such as json and csv, are consumed by tools that do not expect them.
+var encoded = map[string]bool{"text": true, "srt": true, "vtt": true, "lrc": true}
+
+// encode applies the encoding options to the transcript data: a UTF-8 byte
+// order mark and CRLF line endings, if requested.
+func encode(data []byte, opts options) []byte {
+	if opts.crlf {
+		data = bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+	}
+	if opts.bom && !bytes.HasPrefix(data, utf8BOM) {
+		data = append(append([]byte{}, utf8BOM...), data...)
+	}
+	return data
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
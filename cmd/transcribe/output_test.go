@@ -0,0 +1,219 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/format"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+func TestParseOutputTemplate(t *testing.T) {
+	tests := []struct {
+		text string
+		ok   bool
+	}{
+		{"{{.Basename}}.{{.Lang}}.{{.Format}}", true},
+		{"{{.Name}}-{{.Engine}}-{{.Date}}-{{.Run}}.{{.Format}}", true},
+		{"transcript.txt", true},
+		{"{{.Basename", false},        // syntax error
+		{"{{.Speaker}}.txt", false},   // unknown field
+		{"", false},                   // empty
+		{"  ", false},                 // blank
+		{".", false},                  // current directory
+		{"{{.Ext}}/{{.Name}}", false}, // directory
+		{`a\{{.Name}}`, false},        // directory on Windows
+	}
+
+	opts := testOutputOptions(t)
+	for _, tt := range tests {
+		_, err := parseOutputTemplate(tt.text, opts)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseOutputTemplate(%q) = %v, want ok=%v", tt.text, err, tt.ok)
+		}
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	out := t.TempDir()
+
+	tests := []struct {
+		name     string
+		template string
+		fn       func(opts *options)
+		files    []string
+		expected []string
+	}{
+		{
+			"default",
+			"",
+			nil,
+			[]string{"in/a.wav", "in/b.flac"},
+			[]string{filepath.Join(out, "a.wav.txt"), filepath.Join(out, "b.flac.txt")},
+		},
+		{
+			"template",
+			"{{.Basename}}.{{.Lang}}.{{.Ext}}.{{.Format}}",
+			nil,
+			[]string{"in/a.wav"},
+			[]string{filepath.Join(out, "a.en-US.wav.txt")},
+		},
+		{
+			"language",
+			"{{.Basename}}.{{.Lang}}.{{.Format}}",
+			func(opts *options) { opts.config = &speechpb.RecognitionConfig{LanguageCode: "de-DE"} },
+			[]string{"in/a.wav"},
+			[]string{filepath.Join(out, "a.de-DE.txt")},
+		},
+		{
+			"run",
+			"{{.Basename}}-{{.Run}}-{{.Date}}.{{.Format}}",
+			nil,
+			[]string{"in/a.wav"},
+			[]string{filepath.Join(out, "a-test-2017-06-11.txt")},
+		},
+		{
+			"manifest",
+			"{{.Basename}}.{{.Lang}}.{{.Format}}",
+			func(opts *options) {
+				opts.jobs["in/a.wav"] = job{File: "in/a.wav", Output: "first.txt"}
+				opts.jobs["in/b.wav"] = job{File: "in/b.wav", Language: "fr-FR"}
+			},
+			[]string{"in/a.wav", "in/b.wav"},
+			[]string{filepath.Join(out, "first.txt"), filepath.Join(out, "b.fr-FR.txt")},
+		},
+		{
+			"mirrored",
+			"",
+			func(opts *options) { opts.dirs["in/sub/a.wav"] = "sub" },
+			[]string{"in/a.wav", "in/sub/a.wav"},
+			[]string{filepath.Join(out, "a.wav.txt"), filepath.Join(out, "sub", "a.wav.txt")},
+		},
+		{
+			"stdout",
+			"",
+			func(opts *options) { opts.out = "-" },
+			[]string{"in/a.wav"},
+			[]string{"-"},
+		},
+		{
+			"duplicate",
+			"transcript.{{.Format}}",
+			nil,
+			[]string{"in/a.wav", "in/b.wav"},
+			nil,
+		},
+		{
+			"duplicate basename",
+			"{{.Basename}}.{{.Format}}",
+			nil,
+			[]string{"in/a.wav", "in/a.flac"},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		opts := testOutputOptions(t)
+		opts.out = out
+		if tt.fn != nil {
+			tt.fn(&opts)
+		}
+		if tt.template != "" {
+			tmpl, err := parseOutputTemplate(tt.template, opts)
+			if err != nil {
+				t.Fatalf("%v: parseOutputTemplate(%q) failed: %v", tt.name, tt.template, err)
+			}
+			opts.template = tmpl
+		}
+
+		actual, err := outputPaths(tt.files, opts)
+		if tt.expected == nil {
+			if err == nil {
+				t.Errorf("%v: outputPaths(%v) = %v, want error", tt.name, tt.files, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: outputPaths(%v) failed: %v", tt.name, tt.files, err)
+			continue
+		}
+
+		expected := map[string]string{}
+		for i, file := range tt.files {
+			expected[file] = tt.expected[i]
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%v: outputPaths(%v) = %v, want %v", tt.name, tt.files, actual, expected)
+		}
+	}
+}
+
+func TestCheckOutputName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"a.txt", true},
+		{"a b.txt", true},
+		{".a.txt", true},
+		{"", false},
+		{" ", false},
+		{".", false},
+		{"..", false},
+		{"out/a.txt", false},
+		{`out\a.txt`, false},
+		{"/a.txt", false},
+	}
+
+	for _, tt := range tests {
+		if err := checkOutputName(tt.name); (err == nil) != tt.ok {
+			t.Errorf("checkOutputName(%q) = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.wav.txt")
+
+	tests := []struct {
+		existing string // created before the call, if any
+		expected string
+	}{
+		{"", path},
+		{"a.wav.txt", filepath.Join(dir, "a.wav.v2.txt")},
+		{"a.wav.v2.txt", filepath.Join(dir, "a.wav.v3.txt")},
+	}
+
+	for _, tt := range tests {
+		if tt.existing != "" {
+			if err := os.WriteFile(filepath.Join(dir, tt.existing), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if actual := versioned(path); actual != tt.expected {
+			t.Errorf("versioned(%v) with %v = %v, want %v", path, tt.existing, actual, tt.expected)
+		}
+	}
+}
+
+// testOutputOptions returns options for naming text outputs of run "test" on
+// 2017-06-11.
+func testOutputOptions(t *testing.T) options {
+	t.Helper()
+
+	f, err := format.Lookup("text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return options{
+		format: f,
+		run:    "test",
+		date:   time.Date(2017, 6, 11, 0, 0, 0, 0, time.UTC),
+		dirs:   map[string]string{},
+		jobs:   map[string]job{},
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// barWidth is the width of progress bars in characters.
+const barWidth = 30
+
+// bars is a multi-bar progress display of a batch on a terminal, with a bar per
+// file in progress and one for the batch. Log lines written with the standard
+// logger are printed above the bars. A nil display does nothing, so that
+// progress is only logged.
+type bars struct {
+	w  io.Writer
+	tp *throughput
+
+	mu    sync.Mutex
+	files []*bar // in order of start
+	lines int    // number of lines drawn
+	last  time.Time
+}
+
+// bar is the progress of a file, such as 45% uploaded.
+type bar struct {
+	name    string
+	state   string
+	percent int
+}
+
+// newBars returns a progress display for a batch with the given throughput,
+// if enabled and stderr is a terminal. Otherwise, it returns nil.
+func newBars(tp *throughput) *bars {
+	if !*progress || !isTerminal(os.Stderr) {
+		return nil
+	}
+	b := &bars{w: os.Stderr, tp: tp}
+	log.SetOutput(b)
+	return b
+}
+
+// isTerminal returns true iff the file is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// update sets the state and percentage of the file with the given name, such as
+// "uploading" at 45%.
+func (b *bars) update(name, state string, percent int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := b.lookup(name)
+	if p == nil {
+		p = &bar{name: name}
+		b.files = append(b.files, p)
+	}
+	p.state = state
+	p.percent = min(max(percent, 0), 100)
+
+	if time.Since(b.last) > 100*time.Millisecond {
+		b.redraw()
+	}
+}
+
+// remove removes the bar of the file with the given name, if any.
+func (b *bars) remove(name string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, p := range b.files {
+		if p.name == name {
+			b.files = append(b.files[:i], b.files[i+1:]...)
+			break
+		}
+	}
+	b.redraw()
+}
+
+// finish redraws the display after a file of the batch is done, as counted by
+// the throughput.
+func (b *bars) finish() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.redraw()
+}
+
+// stop removes the display and restores logging to stderr.
+func (b *bars) stop() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.clear()
+	log.SetOutput(os.Stderr)
+}
+
+// Write writes a log line above the bars.
+func (b *bars) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.clear()
+	n, err := b.w.Write(p)
+	b.draw()
+	return n, err
+}
+
+func (b *bars) lookup(name string) *bar {
+	for _, p := range b.files {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (b *bars) redraw() {
+	b.clear()
+	b.draw()
+}
+
+// clear erases the drawn lines. The cursor is left at the first of them.
+func (b *bars) clear() {
+	if b.lines > 0 {
+		fmt.Fprintf(b.w, "\x1b[%vA\r\x1b[J", b.lines)
+	}
+	b.lines = 0
+}
+
+func (b *bars) draw() {
+	width := len("batch")
+	for _, p := range b.files {
+		width = max(width, min(len(p.name), barWidth))
+	}
+
+	var sb strings.Builder
+	for _, p := range b.files {
+		fmt.Fprintf(&sb, "%-*v  %v %3v%% %v\n", width, truncate(p.name, barWidth), gauge(p.percent), p.percent, p.state)
+	}
+	b.tp.mu.Lock()
+	done, total := b.tp.done, b.tp.total
+	b.tp.mu.Unlock()
+
+	percent := 0
+	if total > 0 {
+		percent = 100 * done / total
+	}
+	eta := ""
+	if d, _, ok := b.tp.eta(); ok {
+		eta = "ETA " + d.Round(time.Second).String()
+	}
+	fmt.Fprintf(&sb, "%-*v  %v %v/%v files %v\n", width, "batch", gauge(percent), done, total, eta)
+
+	io.WriteString(b.w, sb.String())
+	b.lines = len(b.files) + 1
+	b.last = time.Now()
+}
+
+// gauge returns a bar filled to the given percentage, such as "[=====>     ]".
+func gauge(percent int) string {
+	n := barWidth * percent / 100
+	if n == barWidth {
+		return "[" + strings.Repeat("=", barWidth) + "]"
+	}
+	return "[" + strings.Repeat("=", n) + ">" + strings.Repeat(" ", barWidth-n-1) + "]"
+}
+
+// truncate returns the name shortened to at most n characters, with an ellipsis
+// if shortened.
+func truncate(name string, n int) string {
+	if len(name) <= n {
+		return name
+	}
+	return name[:n-3] + "..."
+}
+
+// throughput tracks the observed throughput of a batch in audio duration per
+// wall time, so that the time to transcribe the remaining audio can be
+// predicted from the files done so far, including parallelism.
+type throughput struct {
+	start time.Time
+	total int
+	audio time.Duration // total audio of known duration
+
+	mu   sync.Mutex
+	done int
+	sum  time.Duration // audio done
+	rate float64       // audio per wall time, if known
+	end  time.Time     // predicted end, if rate is known
+}
+
+func newThroughput(files int, audio time.Duration) *throughput {
+	return &throughput{start: time.Now(), total: files, audio: audio}
+}
+
+// add counts a file of the given audio duration as done and updates the
+// prediction. The audio of failed files is not counted towards the throughput.
+func (t *throughput) add(d time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done++
+	if err != nil {
+		t.audio -= d
+	} else {
+		t.sum += d
+	}
+
+	now := time.Now()
+	if elapsed := now.Sub(t.start); t.sum > 0 && elapsed > 0 {
+		t.rate = float64(t.sum) / float64(elapsed)
+		t.end = now.Add(time.Duration(float64(max(t.audio-t.sum, 0)) / t.rate))
+	}
+}
+
+// eta returns the predicted time until the remaining audio is transcribed and
+// the observed audio minutes per wall minute. It returns false if nothing has
+// been transcribed yet or the batch is done.
+func (t *throughput) eta() (time.Duration, float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.rate == 0 || t.done >= t.total {
+		return 0, 0, false
+	}
+	return max(time.Until(t.end), 0), t.rate, true
+}
+
+// String returns the progress of the batch, such as "3/10 files done, ETA 12m0s
+// at 4.2 audio minutes per minute".
+func (t *throughput) String() string {
+	t.mu.Lock()
+	done := t.done
+	t.mu.Unlock()
+
+	if eta, rate, ok := t.eta(); ok {
+		return fmt.Sprintf("%v/%v files done, ETA %v at %.1f audio minutes per minute", done, t.total, eta.Round(time.Second), rate)
+	}
+	return fmt.Sprintf("%v/%v files done", done, t.total)
+}
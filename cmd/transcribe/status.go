@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/format"
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/filex"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// Transcription statuses of audio files.
+const (
+	statusPartial = "partial" // output left incomplete
+	statusPending = "pending" // not transcribed
+)
+
+// statusMain reports whether the audio files have been transcribed, i.e.,
+// whether run would skip them.
+func statusMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("status", "status [options] file [...]", `Status reports whether audio files have been transcribed: done, if the output
+exists, partial, if the output was left incomplete, or pending. The output
+options must match those given to run.`)
+	out := fs.String("out", ".", "Directory of output files.")
+	outfmt := fs.String("format", "text", "Output format, which determines the output file extension.")
+	outtmpl := fs.String("out-template", "", "Template for output file names, as given to run.")
+	config := fs.String("config-json", "", "File with a Speech API v1 RecognitionConfig in JSON format, as given to run, for the language of output file names.")
+	include := fs.String("include", "", "Comma-separated list of patterns of files to report when searching directories or expanding glob patterns, as given to run.")
+	exclude := fs.String("exclude", "", "Comma-separated list of patterns of files to skip when searching directories or expanding glob patterns, as given to run.")
+	language := fs.String("language", "", "Language code, as given to run, for the language of output file names.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "No files provided.")
+	}
+	f, err := format.Lookup(*outfmt)
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid --format: %v", err)
+	}
+	opts := options{format: f, out: *out, date: time.Now(), dirs: map[string]string{}}
+	if *config != "" {
+		c, err := transcribe.LoadConfig(*config)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid config: %v", err)
+		}
+		opts.config = c
+	}
+	if *language != "" {
+		if opts.config == nil {
+			opts.config = &speechpb.RecognitionConfig{}
+		}
+		opts.config.LanguageCode = *language
+	}
+	if *outtmpl != "" {
+		tmpl, err := parseOutputTemplate(*outtmpl, opts)
+		if err != nil {
+			return exitErrorf(exitConfig, "Invalid --out-template: %v", err)
+		}
+		opts.template = tmpl
+	}
+
+	files, cleanup, err := inputs(ctx, fs.Args(), selection{include: *include, exclude: *exclude}, opts.dirs)
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid files: %v", err)
+	}
+	defer cleanup()
+
+	outs, err := outputPaths(files, opts)
+	if err != nil {
+		return exitErrorf(exitConfig, "Invalid output names: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATUS\tOUTPUT")
+	for _, file := range files {
+		output := outs[file]
+
+		status := statusPending
+		if _, err := os.Stat(output); err == nil {
+			status = statusDone
+			if filex.Partial(output) {
+				status = statusPartial
+			}
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", file, status, output)
+	}
+	tw.Flush()
+	return nil
+}
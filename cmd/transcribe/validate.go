@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/audio"
+	"github.com/herohde/transcribe/pkg/audio/flac"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/transcribe"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+)
+
+// problem is an input file that cannot be transcribed, with a suggested fix.
+type problem struct {
+	File, Problem, Fix string
+}
+
+// validate checks that the files are readable, in a supported format and within
+// the limits of the backend before anything is uploaded. It returns the problems
+// found, if any.
+func validate(ctx context.Context, files []string, caps transcribe.Capabilities, opts options) []problem {
+	var ret []problem
+	for _, file := range files {
+		if p, ok := check(ctx, file, caps, opts); !ok {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// check returns the problem with the given file, if any.
+func check(ctx context.Context, file string, caps transcribe.Capabilities, opts options) (problem, bool) {
+	if isObject(file) {
+		return checkObject(ctx, file, caps, opts)
+	}
+
+	fd, err := os.Open(file)
+	if err != nil {
+		return problem{file, fmt.Sprintf("not readable: %v", err), "Check the path and file permissions"}, false
+	}
+	fd.Close()
+
+	if isPCM(file) {
+		if opts.pcm == nil {
+			return problem{file, "headerless PCM with unknown format", "Provide the format with --pcm-rate, --pcm-channels and --pcm-bits"}, false
+		}
+		return problem{}, true
+	}
+
+	rate := 0
+	switch {
+	case isWAV(file):
+		h, err := wav.ReadFile(file)
+		if err != nil {
+			return problem{file, fmt.Sprintf("invalid wav header: %v", err), "Re-export the file as 16-bit PCM wav or flac"}, false
+		}
+		if !isNative(h) && !wav.Supported(h) {
+			if _, err := audio.Find(file, audio.Options{}); err != nil {
+				return problem{file, fmt.Sprintf("unsupported wav format: %v", h), "Install sox or ffmpeg, or re-export the file as 16-bit PCM wav"}, false
+			}
+		}
+		rate = h.SampleRate
+
+	case isFLAC(file):
+		h, err := flac.ReadFile(file)
+		if err != nil {
+			return problem{file, fmt.Sprintf("invalid flac header: %v", err), "Re-export the file as flac or 16-bit PCM wav"}, false
+		}
+		rate = h.SampleRate
+	}
+
+	if rate != 0 && (rate < transcribe.MinSampleRate || rate > transcribe.MaxSampleRate) {
+		if _, err := audio.Find(file, audio.Options{SampleRate: defaultSampleRate}); err != nil {
+			return problem{file, fmt.Sprintf("unsupported sample rate: %vHz", rate), fmt.Sprintf("Install sox or ffmpeg, or resample to 16kHz, e.g., 'sox %v -r 16000 out.wav'", filepath.Base(file))}, false
+		}
+	}
+
+	if n := channels(file); n > 0 {
+		for _, c := range opts.selected {
+			if c > n {
+				return problem{file, fmt.Sprintf("channel %v not present: file has %v channels", c, n), fmt.Sprintf("Select channels 1-%v with --channels", n)}, false
+			}
+		}
+	}
+
+	if caps.MaxDuration > 0 && !(isWAV(file) && opts.chunk > 0 && opts.chunk <= caps.MaxDuration) {
+		if d := opts.durations[file]; d > caps.MaxDuration {
+			return problem{file, fmt.Sprintf("too long: %v exceeds the maximum of %v per request", d.Round(time.Second), caps.MaxDuration), fmt.Sprintf("Split the file with --chunk=%v", caps.MaxDuration/2)}, false
+		}
+	}
+
+	aopts := audio.Options{Mono: opts.mono, Normalize: opts.loudnorm, SampleRate: targetRate(file, opts), Channels: opts.selected, Track: opts.track}
+	if needsConversion(file, aopts) {
+		if _, err := audio.Find(file, aopts); err != nil {
+			return problem{file, "no converter available", "Install ffmpeg (or sox for mp3 and wav)"}, false
+		}
+	}
+	return problem{}, true
+}
+
+// checkObject returns the problem with the given GCS object, if any. Flac and
+// wav objects are transcribed in place and thus cannot be converted. Their
+// header is read without downloading them.
+func checkObject(ctx context.Context, uri string, caps transcribe.Capabilities, opts options) (problem, bool) {
+	if _, _, err := storagex.ParseURI(uri); err != nil {
+		return problem{uri, err.Error(), "Use a URI such as gs://bucket/path/foo.wav"}, false
+	}
+	if opts.mono || opts.loudnorm || opts.rate > 0 || opts.track > 0 || len(opts.selected) > 0 || opts.silence.MinDuration > 0 || opts.chunk > 0 {
+		return problem{uri, "GCS object cannot be converted, trimmed or split", "Transcribe a local copy or omit --mono, --normalize, --sample-rate, --track, --channels, --trim-silence and --chunk"}, false
+	}
+
+	_, channels, err := readObject(ctx, opts.storage, uri)
+	if err != nil {
+		return problem{uri, fmt.Sprintf("not readable: %v", err), "Check the URI and object permissions"}, false
+	}
+	if channels > 1 && !opts.separate {
+		return problem{uri, fmt.Sprintf("GCS object has %v channels and cannot be downmixed", channels), "Use --separate-channels or transcribe a local copy"}, false
+	}
+	if d := opts.durations[uri]; caps.MaxDuration > 0 && d > caps.MaxDuration {
+		return problem{uri, fmt.Sprintf("too long: %v exceeds the maximum of %v per request", d.Round(time.Second), caps.MaxDuration), "Transcribe a local copy with --chunk"}, false
+	}
+	return problem{}, true
+}
+
+// printProblems prints the problems as a table.
+func printProblems(w io.Writer, problems []problem) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tPROBLEM\tSUGGESTED FIX")
+	for _, p := range problems {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", p.File, p.Problem, p.Fix)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/seekerror/logw"
+)
+
+// watchMain monitors a directory and transcribes new audio files as they
+// appear, until interrupted.
+func watchMain(ctx context.Context, args []string) error {
+	fs := newFlagSet("watch", "watch [options] dir [run options]", `Watch monitors a directory and transcribes new audio files as they appear,
+such as in a drop folder of a recording machine. Outputs are written next to
+the audio files, unless --out is given as a run option. Audio files present at
+start that have not been transcribed are transcribed as well. Files are
+transcribed once they have not changed for the settle duration. Files that
+fail are retried with exponential backoff up to the maximum number of attempts,
+after which they must be transcribed by a manual run. Subdirectories are not
+watched.`)
+	settle := fs.Duration("settle", 10*time.Second, "Duration a new file must be unchanged before it is transcribed, so that files still being written are not transcribed.")
+	attempts := fs.Int("max-attempts", 3, "Maximum number of runs for a file that fails to transcribe. Retries back off exponentially from --retry-delay.")
+	delay := fs.Duration("retry-delay", time.Minute, "Delay before the first retry of a file that failed to transcribe.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "No directory provided.")
+	}
+	dir := fs.Arg(0)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		fs.Usage()
+		return exitErrorf(exitConfig, "Invalid directory: %v", dir)
+	}
+	if *settle <= 0 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "Invalid --settle: %v. Must be positive.", *settle)
+	}
+	if *attempts < 1 || *delay < 0 {
+		fs.Usage()
+		return exitErrorf(exitConfig, "Invalid retries: %v attempts must be at least 1 and delay %v not negative.", *attempts, *delay)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return exitErrorf(exitSetup, "Failed to locate executable: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return exitErrorf(exitSetup, "Failed to watch %v: %v", dir, err)
+	}
+	defer w.Close()
+
+	if err := w.Add(dir); err != nil {
+		return exitErrorf(exitSetup, "Failed to watch %v: %v", dir, err)
+	}
+
+	// Files are pending until they settle. Files present at start are pending
+	// as well. Already transcribed files are skipped by run. Batches run in the
+	// background, so that events are not dropped while transcribing. Failed
+	// files are pending again after a delay.
+
+	pending := map[string]time.Time{}
+	scan(ctx, dir, pending, time.Time{})
+
+	done := make(chan outcome, 1)
+	var batch []string           // files being transcribed, if any
+	failures := map[string]int{} // failed runs by file
+
+	infof(ctx, "Watching %v for new audio files", dir)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if e.Has(fsnotify.Create) || e.Has(fsnotify.Write) {
+				if isWatched(e.Name) {
+					pending[e.Name] = time.Now()
+				}
+			}
+			if e.Has(fsnotify.Remove) || e.Has(fsnotify.Rename) {
+				delete(pending, e.Name)
+				delete(failures, e.Name)
+			}
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// Events were lost: rescan for files that may have changed.
+				logw.Errorf(ctx, "Events of %v were dropped. Rescanning", dir)
+				scan(ctx, dir, pending, time.Now())
+				continue
+			}
+			logw.Errorf(ctx, "Failed to watch %v: %v", dir, err)
+
+		case o := <-done:
+			if o.err != nil {
+				logw.Errorf(ctx, "Failed to transcribe %v: %v", strings.Join(o.failed, ", "), o.err)
+			}
+
+			retry := map[string]bool{}
+			for _, file := range o.failed {
+				retry[file] = true
+			}
+			for _, file := range batch {
+				if !retry[file] {
+					delete(failures, file)
+					continue
+				}
+
+				failures[file]++
+				if n := failures[file]; n >= *attempts {
+					logw.Errorf(ctx, "Giving up on %v after %v attempts. Transcribe it with run", file, n)
+					delete(failures, file)
+					continue
+				}
+				if _, ok := pending[file]; !ok {
+					pending[file] = time.Now().Add(backoff(*delay, failures[file]))
+				}
+			}
+			batch = nil
+
+		case <-ticker.C:
+			if batch != nil {
+				continue // wait for the running batch
+			}
+
+			var files []string
+			for file, last := range pending {
+				if time.Since(last) >= *settle {
+					files = append(files, file)
+					delete(pending, file)
+				}
+			}
+			if len(files) == 0 {
+				continue
+			}
+			sort.Strings(files)
+
+			batch = files
+			go func() {
+				done <- transcribeNew(ctx, self, dir, files, fs.Args()[1:])
+			}()
+
+		case <-ctx.Done():
+			if batch != nil {
+				<-done // the run is stopped with the context
+			}
+			infof(ctx, "Stopped watching %v", dir)
+			return nil
+		}
+	}
+}
+
+// scan marks the audio files in the directory as pending since the given time,
+// unless already pending.
+func scan(ctx context.Context, dir string, pending map[string]time.Time, since time.Time) {
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		logw.Errorf(ctx, "Failed to read %v: %v", dir, err)
+		return
+	}
+	for _, e := range list {
+		name := filepath.Join(dir, e.Name())
+		if _, ok := pending[name]; !ok && !e.IsDir() && isWatched(name) {
+			pending[name] = since
+		}
+	}
+}
+
+// isWatched returns true iff the file is a supported audio file, excluding
+// hidden and temporary files.
+func isWatched(file string) bool {
+	name := filepath.Base(file)
+	return !strings.HasPrefix(name, ".") && isSupported(name)
+}
+
+// outcome is the outcome of transcribing a batch of files.
+type outcome struct {
+	failed []string // files to retry, if any
+	err    error
+}
+
+// transcribeNew transcribes the files with the run command in a separate
+// process, so that a failed run does not stop watching. Outputs are written to
+// the watched directory, unless overridden by the run options. The failed
+// files are read from the summary of the run. If not known, all files failed.
+func transcribeNew(ctx context.Context, self, dir string, files, opts []string) outcome {
+	infof(ctx, "Transcribing %v new audio files", len(files))
+
+	fd, err := os.CreateTemp("", "transcribe-summary-*.json")
+	if err != nil {
+		return outcome{failed: files, err: err}
+	}
+	fd.Close()
+	defer os.Remove(fd.Name())
+
+	args := append([]string{"run", "--out", dir, "--summary", fd.Name()}, opts...)
+	args = append(args, files...)
+
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	var exit *exec.ExitError
+	if err := cmd.Run(); err != nil && !(errors.As(err, &exit) && exit.ExitCode() == exitNothing) {
+		return outcome{failed: failedFiles(fd.Name(), files), err: err}
+	}
+	return outcome{}
+}
+
+// failedFiles returns the files that failed according to the summary of their
+// run. It returns all files if the summary cannot be read, such as if the run
+// failed before transcribing them.
+func failedFiles(filename string, files []string) []string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return files
+	}
+	var sum summary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		return files
+	}
+
+	failed := map[string]bool{}
+	for _, f := range sum.Failures {
+		failed[f.File] = true
+	}
+	var ret []string
+	for _, file := range files {
+		if failed[filepath.Base(file)] {
+			ret = append(ret, file)
+		}
+	}
+	if len(ret) == 0 {
+		return files // not failures of files, such as of the sink
+	}
+	return ret
+}
+
+// maxBackoff is the maximum delay before retrying a failed file.
+const maxBackoff = time.Hour
+
+// backoff returns the delay before retrying a file that failed the given number
+// of times. It doubles with each failure up to the maximum.
+func backoff(delay time.Duration, failures int) time.Duration {
+	ret := delay
+	for i := 1; i < failures && ret < maxBackoff; i++ {
+		ret *= 2
+	}
+	return min(ret, maxBackoff)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		delay    time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{time.Minute, 1, time.Minute},
+		{time.Minute, 2, 2 * time.Minute},
+		{time.Minute, 3, 4 * time.Minute},
+		{time.Minute, 7, maxBackoff},
+		{time.Minute, 100, maxBackoff},
+		{2 * time.Hour, 1, maxBackoff},
+		{0, 3, 0},
+	}
+
+	for _, tt := range tests {
+		if actual := backoff(tt.delay, tt.failures); actual != tt.expected {
+			t.Errorf("backoff(%v, %v) = %v, want %v", tt.delay, tt.failures, actual, tt.expected)
+		}
+	}
+}
+
+func TestFailedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "a.wav"), filepath.Join(dir, "b.wav"), filepath.Join(dir, "c.wav")}
+
+	tests := []struct {
+		name     string
+		data     string // summary, if any
+		expected []string
+	}{
+		{"some.json", `{"failures": [{"file": "a.wav", "class": "other"}, {"file": "c.wav", "class": "quota"}]}`, []string{files[0], files[2]}},
+		{"sink.json", `{"failures": [{"class": "other"}]}`, files},
+		{"empty.json", "", files},
+		{"invalid.json", "{", files},
+		{"missing.json", "-", files},
+	}
+
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.name)
+		if tt.data != "-" {
+			if err := os.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if actual := failedFiles(filename, files); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("failedFiles(%v) = %v, want %v", tt.name, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package audio contains converters of audio files to formats supported by the
+// Speech API. Converters are registered by name and selected by input format,
+// so new formats can be supported by registering a converter.
+package audio
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Options are conversion options.
+type Options struct {
+	// Mono indicates that the audio is downmixed to mono.
+	Mono bool
+	// Normalize indicates that the loudness of the audio is normalized.
+	Normalize bool
+	// SampleRate is the target sample rate in Hz. If zero, the sample rate is
+	// unchanged.
+	SampleRate int
+	// Channels are the channels to keep, 1-based, such as from a multitrack
+	// session export. If empty, all channels are kept.
+	Channels []int
+	// Track is the audio track to use, 1-based, for containers with multiple
+	// audio tracks, such as videos with a commentary track. If zero, the first
+	// (default) track is used.
+	Track int
+}
+
+// Converter converts audio files to 16-bit wav or flac.
+type Converter interface {
+	// Name returns the name of the converter, such as "sox".
+	Name() string
+	// Formats returns the input file extensions supported by the converter,
+	// such as ".mp3".
+	Formats() []string
+	// Ext returns the file extension of converted files, such as ".wav".
+	Ext() string
+	// Probe returns an error if the converter cannot convert the given file
+	// with the given options, such as if an external tool is not installed.
+	Probe(filename string, opts Options) error
+	// Convert converts the given file into the output file.
+	Convert(ctx context.Context, in, out string, opts Options) error
+}
+
+// converters are the registered converters in order of preference.
+var converters []Converter
+
+func init() {
+	Register(native{})
+	Register(sox{})
+	Register(ffmpeg{})
+}
+
+// Register registers a converter. Earlier registered converters are preferred.
+func Register(c Converter) {
+	converters = append(converters, c)
+}
+
+// Lookup returns the registered converter with the given name.
+func Lookup(name string) (Converter, error) {
+	for _, c := range converters {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown converter: %v", name)
+}
+
+// Find returns the first registered converter that can convert the given file
+// with the given options.
+func Find(filename string, opts Options) (Converter, error) {
+	var reasons []string
+	for _, c := range converters {
+		err := c.Probe(filename, opts)
+		if err == nil {
+			return c, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%v: %v", c.Name(), err))
+	}
+	return nil, fmt.Errorf("no converter for %v (%v)", filepath.Base(filename), strings.Join(reasons, "; "))
+}
+
+// Formats returns the input file extensions supported by any registered
+// converter.
+func Formats() []string {
+	m := map[string]bool{}
+	for _, c := range converters {
+		for _, f := range c.Formats() {
+			m[f] = true
+		}
+	}
+
+	var ret []string
+	for f := range m {
+		ret = append(ret, f)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// hasFormat returns true iff the file has one of the given extensions.
+func hasFormat(filename string, formats []string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, f := range formats {
+		if ext == f {
+			return true
+		}
+	}
+	return false
+}
+
+// join returns the channels as a comma-separated list, such as "1,3".
+func join(channels []int) string {
+	var list []string
+	for _, c := range channels {
+		list = append(list, fmt.Sprint(c))
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/herohde/transcribe/pkg/audio/flac"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+// maxNormalizedRate is the highest sample rate in Hz of normalized audio. It is
+// also used if the source rate is not known.
+const maxNormalizedRate = 48000
+
+// ffmpeg transcodes audio files to flac using ffmpeg. For video files, the
+// (selected) audio track is extracted. Normalization is to EBU R128 loudness.
+type ffmpeg struct{}
+
+func (ffmpeg) Name() string {
+	return "ffmpeg"
+}
+
+func (ffmpeg) Formats() []string {
+	return []string{".wav", ".flac", ".mp3", ".m4a", ".aac", ".mp4", ".mkv", ".mov"}
+}
+
+func (ffmpeg) Ext() string {
+	return ".flac"
+}
+
+func (f ffmpeg) Probe(filename string, opts Options) error {
+	if !hasFormat(filename, f.Formats()) {
+		return fmt.Errorf("unsupported format")
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("not installed")
+	}
+	return nil
+}
+
+func (ffmpeg) Convert(ctx context.Context, in, out string, opts Options) error {
+	args := []string{"-nostdin", "-y", "-i", pathx.External(in), "-vn"}
+	if opts.Track > 0 {
+		args = append(args, "-map", fmt.Sprintf("0:a:%v", opts.Track-1))
+	}
+
+	var filters []string
+	switch {
+	case len(opts.Channels) > 0 && opts.Mono:
+		var mix []string
+		for _, c := range opts.Channels {
+			mix = append(mix, fmt.Sprintf("%.4g*c%v", 1/float64(len(opts.Channels)), c-1))
+		}
+		filters = append(filters, "pan=mono|c0="+strings.Join(mix, "+"))
+	case len(opts.Channels) > 0:
+		pan := fmt.Sprintf("pan=%vc", len(opts.Channels))
+		for i, c := range opts.Channels {
+			pan += fmt.Sprintf("|c%v=c%v", i, c-1)
+		}
+		filters = append(filters, pan)
+	case opts.Mono:
+		args = append(args, "-ac", "1")
+	}
+	if opts.Normalize {
+		filters = append(filters, "loudnorm")
+	}
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+	rate := opts.SampleRate
+	if rate == 0 && opts.Normalize {
+		rate = normalizedRate(ctx, in) // loudnorm resamples to 192kHz
+	}
+	if rate > 0 {
+		args = append(args, "-ar", fmt.Sprint(rate))
+	}
+	args = append(args, "-c:a", "flac", pathx.External(out))
+
+	if data, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed (err=%v): %v", err, string(data))
+	}
+	return nil
+}
+
+// normalizedRate returns the sample rate of the file, capped at the highest
+// rate of normalized audio. The rate of formats other than wav and flac is
+// probed with ffprobe, if installed.
+func normalizedRate(ctx context.Context, filename string) int {
+	rate := maxNormalizedRate
+	if h, err := wav.ReadFile(filename); err == nil {
+		rate = h.SampleRate
+	} else if h, err := flac.ReadFile(filename); err == nil {
+		rate = h.SampleRate
+	} else if data, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-select_streams", "a:0", "-show_entries", "stream=sample_rate", "-of", "csv=p=0", pathx.External(filename)).Output(); err == nil {
+		if r, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			rate = r
+		}
+	}
+	if rate <= 0 || rate > maxNormalizedRate {
+		return maxNormalizedRate
+	}
+	return rate
+}
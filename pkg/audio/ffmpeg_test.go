@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/herohde/transcribe/pkg/audio/flac"
+	"github.com/herohde/transcribe/pkg/audio/wav"
+)
+
+func TestNormalizedRate(t *testing.T) {
+	tests := []struct {
+		rate     int
+		expected int
+	}{
+		{8000, 8000},
+		{16000, 16000},
+		{44100, 44100},
+		{48000, 48000},
+		{96000, 48000},
+		{192000, 48000},
+	}
+
+	for _, tt := range tests {
+		filename := writeTone(t, tt.rate, 1)
+		if actual := normalizedRate(context.Background(), filename); actual != tt.expected {
+			t.Errorf("normalizedRate(%v) = %v, want %v", tt.rate, actual, tt.expected)
+		}
+	}
+}
+
+func TestFfmpegNormalizeRate(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	tests := []struct {
+		rate, target int
+		expected     int
+	}{
+		{44100, 0, 44100},
+		{96000, 0, 48000},
+		{44100, 16000, 16000},
+	}
+
+	for _, tt := range tests {
+		in := writeTone(t, tt.rate, 2)
+		out := filepath.Join(t.TempDir(), "out.flac")
+
+		opts := Options{Normalize: true, SampleRate: tt.target}
+		if err := (ffmpeg{}).Convert(context.Background(), in, out, opts); err != nil {
+			t.Fatalf("Convert(%v, %v) failed: %v", tt.rate, tt.target, err)
+		}
+		h, err := flac.ReadFile(out)
+		if err != nil {
+			t.Fatalf("ReadFile(%v) failed: %v", out, err)
+		}
+		if h.SampleRate != tt.expected {
+			t.Errorf("Convert(%v, %v) = %vHz, want %vHz", tt.rate, tt.target, h.SampleRate, tt.expected)
+		}
+	}
+}
+
+// writeTone writes a mono 16-bit wav file with a 440Hz tone of the given
+// duration in seconds at the given sample rate.
+func writeTone(t *testing.T, rate, seconds int) string {
+	t.Helper()
+
+	frames := rate * seconds
+	h := &wav.Header{Format: wav.FormatPCM, Channels: 1, SampleRate: rate, BitsPerSample: 16, DataSize: int64(2 * frames)}
+
+	filename := filepath.Join(t.TempDir(), "tone.wav")
+	fd, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if err := wav.WriteHeader(fd, h); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2*frames)
+	for i := 0; i < frames; i++ {
+		v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+	if _, err := fd.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
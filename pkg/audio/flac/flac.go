@@ -0,0 +1,76 @@
+// Package flac contains utilities for reading FLAC audio file headers.
+package flac
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFLAC indicates that the data is not a FLAC file.
+var ErrNotFLAC = errors.New("not a FLAC file")
+
+// Header is the stream information of a FLAC file.
+type Header struct {
+	// Channels is the number of channels.
+	Channels int
+	// SampleRate is the number of samples per second.
+	SampleRate int
+	// BitsPerSample is the bit depth, such as 16.
+	BitsPerSample int
+	// Samples is the total number of samples per channel. Zero if unknown.
+	Samples int64
+}
+
+// Duration returns the duration of the audio. Zero if unknown.
+func (h *Header) Duration() time.Duration {
+	if h.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(h.Samples) * time.Second / time.Duration(h.SampleRate)
+}
+
+func (h *Header) String() string {
+	return fmt.Sprintf("flac[channels=%v, rate=%vHz, bits=%v, duration=%v]", h.Channels, h.SampleRate, h.BitsPerSample, h.Duration())
+}
+
+// ReadFile reads the header of the given FLAC file.
+func ReadFile(filename string) (*Header, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return ReadHeader(fd)
+}
+
+// ReadHeader reads the STREAMINFO header of a FLAC file from the given reader.
+func ReadHeader(r io.Reader) (*Header, error) {
+	// "fLaC", metadata block header (type 0 = STREAMINFO, 24-bit length 34)
+	// and the 34-byte STREAMINFO block.
+
+	var buf [4 + 4 + 34]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, ErrNotFLAC
+	}
+	if string(buf[0:4]) != "fLaC" || buf[4]&0x7f != 0 {
+		return nil, ErrNotFLAC
+	}
+
+	info := buf[8:]
+
+	// Bytes 10-17: sample rate (20 bits), channels-1 (3 bits), bits-1 (5 bits)
+	// and total samples (36 bits).
+
+	v := binary.BigEndian.Uint64(info[10:18])
+	return &Header{
+		SampleRate:    int(v >> 44),
+		Channels:      int((v>>41)&0x7) + 1,
+		BitsPerSample: int((v>>36)&0x1f) + 1,
+		Samples:       int64(v & 0xfffffffff),
+	}, nil
+}
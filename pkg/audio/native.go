@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/herohde/transcribe/pkg/audio/wav"
+)
+
+// native converts and resamples wav files to 16-bit PCM in-process. It does not
+// require any external tool, but does not support normalization.
+type native struct{}
+
+func (native) Name() string {
+	return "native"
+}
+
+func (native) Formats() []string {
+	return []string{".wav"}
+}
+
+func (native) Ext() string {
+	return ".wav"
+}
+
+func (native) Probe(filename string, opts Options) error {
+	if !hasFormat(filename, []string{".wav"}) {
+		return fmt.Errorf("unsupported format")
+	}
+	if opts.Normalize {
+		return fmt.Errorf("normalization not supported")
+	}
+	if opts.Track > 0 {
+		return fmt.Errorf("track selection not supported")
+	}
+	h, err := wav.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !wav.Supported(h) {
+		return fmt.Errorf("unsupported format: %v", h)
+	}
+	return nil
+}
+
+func (native) Convert(ctx context.Context, in, out string, opts Options) error {
+	return wav.Convert(in, out, wav.ConvertOptions{Mono: opts.Mono, SampleRate: opts.SampleRate, Channels: opts.Channels})
+}
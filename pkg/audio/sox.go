@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+// sox converts audio files to 16-bit wav using sox. Normalization is to a peak
+// level of -1dBFS.
+type sox struct{}
+
+func (sox) Name() string {
+	return "sox"
+}
+
+func (sox) Formats() []string {
+	return []string{".wav", ".flac", ".mp3"}
+}
+
+func (sox) Ext() string {
+	return ".wav"
+}
+
+func (s sox) Probe(filename string, opts Options) error {
+	if !hasFormat(filename, s.Formats()) {
+		return fmt.Errorf("unsupported format")
+	}
+	if opts.Track > 0 {
+		return fmt.Errorf("track selection not supported")
+	}
+	if _, err := exec.LookPath("sox"); err != nil {
+		return fmt.Errorf("not installed")
+	}
+	return nil
+}
+
+func (sox) Convert(ctx context.Context, in, out string, opts Options) error {
+	args := []string{pathx.External(in), "-b", "16"}
+	if opts.SampleRate > 0 {
+		args = append(args, "-r", fmt.Sprint(opts.SampleRate))
+	}
+	args = append(args, pathx.External(out))
+	switch {
+	case len(opts.Channels) > 0 && opts.Mono:
+		args = append(args, "remix", join(opts.Channels))
+	case len(opts.Channels) > 0:
+		args = append(args, "remix")
+		for _, c := range opts.Channels {
+			args = append(args, fmt.Sprint(c))
+		}
+	case opts.Mono:
+		args = append(args, "remix", "1-2")
+	}
+	if opts.Normalize {
+		args = append(args, "gain", "-n", "-1")
+	}
+
+	if data, err := exec.CommandContext(ctx, "sox", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("sox failed (err=%v): %v", err, string(data))
+	}
+	return nil
+}
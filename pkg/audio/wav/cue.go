@@ -0,0 +1,112 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Cue is a cue point of a WAV file, such as a marker set while recording.
+type Cue struct {
+	// Offset is the position of the cue in the audio.
+	Offset time.Duration
+	// Label is the label of the cue, if any.
+	Label string
+}
+
+// ReadCues reads the cue points of the given WAV file, ordered by offset. Labels
+// are read from the associated data list, if present.
+func ReadCues(filename string) ([]Cue, error) {
+	fd, h, err := open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	if h.SampleRate == 0 {
+		return nil, fmt.Errorf("invalid WAV file: %v", h)
+	}
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := map[uint32]uint32{} // cue ID -> sample offset
+	labels := map[uint32]string{}
+
+	pos := int64(12)
+	for {
+		if _, err := fd.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		var chunk [8]byte
+		if _, err := io.ReadFull(fd, chunk[:]); err != nil {
+			break // end of file
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		if id == "data" {
+			size = h.DataSize // possibly repaired
+		}
+
+		if (id == "cue " || id == "LIST") && size > fi.Size()-pos-8 {
+			return nil, fmt.Errorf("invalid WAV file: truncated %q chunk", id)
+		}
+
+		switch id {
+		case "cue ":
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(fd, buf); err != nil {
+				return nil, fmt.Errorf("invalid WAV file: truncated cue chunk: %v", err)
+			}
+			for i := 4; i+24 <= len(buf); i += 24 {
+				offsets[binary.LittleEndian.Uint32(buf[i:])] = binary.LittleEndian.Uint32(buf[i+20:])
+			}
+
+		case "LIST":
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(fd, buf); err != nil {
+				return nil, fmt.Errorf("invalid WAV file: truncated LIST chunk: %v", err)
+			}
+			if len(buf) >= 4 && string(buf[0:4]) == "adtl" {
+				readLabels(buf[4:], labels)
+			}
+		}
+		pos += 8 + size + size%2
+	}
+
+	var ret []Cue
+	for id, offset := range offsets {
+		ret = append(ret, Cue{
+			Offset: time.Duration(offset) * time.Second / time.Duration(h.SampleRate),
+			Label:  labels[id],
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Offset < ret[j].Offset
+	})
+	return ret, nil
+}
+
+// readLabels reads the "labl" sub-chunks of an associated data list.
+func readLabels(buf []byte, labels map[uint32]string) {
+	for len(buf) >= 8 {
+		id := string(buf[0:4])
+		size := int(binary.LittleEndian.Uint32(buf[4:8]))
+		if size > len(buf)-8 {
+			return
+		}
+		if id == "labl" && size >= 4 {
+			text := buf[12 : 8+size]
+			if i := bytes.IndexByte(text, 0); i >= 0 {
+				text = text[:i]
+			}
+			labels[binary.LittleEndian.Uint32(buf[8:12])] = string(text)
+		}
+		buf = buf[min(len(buf), 8+size+size%2):]
+	}
+}
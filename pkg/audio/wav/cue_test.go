@@ -0,0 +1,81 @@
+package wav
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadCues(t *testing.T) {
+	format := fmtChunk(FormatPCM, 1, 8000, 16, 16)
+	data := rawChunk("data", make([]byte, 32000)) // 2s
+
+	cues := rawChunk("cue ", cuePoints(map[uint32]uint32{1: 8000, 2: 4000, 3: 12000}))
+	adtl := rawChunk("LIST", append([]byte("adtl"), append(label(1, "Second"), label(2, "First\x00")...)...))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []Cue
+	}{
+		{"none", riff(format, data), nil},
+		{"cues", riff(format, data, cues), []Cue{{Offset: 500 * time.Millisecond}, {Offset: time.Second}, {Offset: 1500 * time.Millisecond}}},
+		{"labels", riff(format, data, adtl, cues), []Cue{{500 * time.Millisecond, "First"}, {time.Second, "Second"}, {Offset: 1500 * time.Millisecond}}},
+		{"odd label", riff(format, data, rawChunk("LIST", append([]byte("adtl"), label(1, "Odd")...)), rawChunk("cue ", cuePoints(map[uint32]uint32{1: 0}))), []Cue{{0, "Odd"}}},
+		{"truncated", append(riff(format, data), append([]byte("cue "), le32(1000)...)...), nil},
+	}
+
+	for _, tt := range tests {
+		filename := filepath.Join(t.TempDir(), "cues.wav")
+		if err := os.WriteFile(filename, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := ReadCues(filename)
+		if tt.name == "truncated" {
+			if err == nil {
+				t.Errorf("ReadCues(%v) = %v, want error", tt.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ReadCues(%v) failed: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ReadCues(%v) = %v, want %v", tt.name, actual, tt.expected)
+		}
+	}
+}
+
+// rawChunk returns a chunk with the given data, padded to an even size.
+func rawChunk(id string, data []byte) []byte {
+	ret := append([]byte(id), le32(uint32(len(data)))...)
+	ret = append(ret, data...)
+	if len(data)%2 == 1 {
+		ret = append(ret, 0)
+	}
+	return ret
+}
+
+// cuePoints returns the data of a cue chunk with the given sample offsets by
+// cue ID.
+func cuePoints(offsets map[uint32]uint32) []byte {
+	ret := le32(uint32(len(offsets)))
+	for id, offset := range offsets {
+		var buf [24]byte
+		binary.LittleEndian.PutUint32(buf[0:], id)
+		copy(buf[8:12], "data")
+		binary.LittleEndian.PutUint32(buf[20:], offset)
+		ret = append(ret, buf[:]...)
+	}
+	return ret
+}
+
+// label returns a labl sub-chunk for the given cue ID.
+func label(id uint32, text string) []byte {
+	return rawChunk("labl", append(le32(id), text...))
+}
@@ -0,0 +1,89 @@
+package wav
+
+import "math"
+
+// zeroCrossings is the number of zero crossings of the sinc filter on each side.
+const zeroCrossings = 16
+
+// resampler converts a stream of samples from one sample rate to another using
+// windowed sinc interpolation. When downsampling, the filter cutoff is lowered
+// to avoid aliasing.
+type resampler struct {
+	from, to int64   // sample rates
+	cutoff   float64 // normalized cutoff frequency in (0;1]
+	width    int64   // half width of the filter in input samples
+
+	buf  []float64 // buffered input samples
+	base int64     // input index of buf[0]
+	n    int64     // index of the next output sample
+}
+
+func newResampler(from, to int) *resampler {
+	cutoff := math.Min(1, float64(to)/float64(from))
+	return &resampler{
+		from:   int64(from),
+		to:     int64(to),
+		cutoff: cutoff,
+		width:  int64(math.Ceil(zeroCrossings / cutoff)),
+	}
+}
+
+// push adds an input sample and appends any output samples that can be computed.
+func (r *resampler) push(v float64, out []float64) []float64 {
+	r.buf = append(r.buf, v)
+	for r.n*r.from/r.to+r.width < r.base+int64(len(r.buf)) {
+		out = append(out, r.sample())
+		r.n++
+	}
+
+	// Drop input samples that are no longer needed, in batches.
+	if drop := r.n*r.from/r.to - r.width - r.base; drop > 4096 {
+		r.buf = append(r.buf[:0], r.buf[drop:]...)
+		r.base += drop
+	}
+	return out
+}
+
+// flush appends the remaining output samples, assuming silence after the end of
+// the input.
+func (r *resampler) flush(out []float64) []float64 {
+	total := r.base + int64(len(r.buf))
+	for r.n*r.from < total*r.to {
+		out = append(out, r.sample())
+		r.n++
+	}
+	return out
+}
+
+// sample returns the output sample at index n.
+func (r *resampler) sample() float64 {
+	pos := float64(r.n*r.from) / float64(r.to)
+	center := r.n * r.from / r.to
+
+	sum := 0.0
+	for i := center - r.width + 1; i <= center+r.width; i++ {
+		j := i - r.base
+		if j < 0 || j >= int64(len(r.buf)) {
+			continue
+		}
+		d := pos - float64(i)
+		if math.Abs(d) >= float64(r.width) {
+			continue
+		}
+		w := 0.5 * (1 + math.Cos(math.Pi*d/float64(r.width))) // Hann window
+		sum += r.buf[j] * sinc(d*r.cutoff) * w
+	}
+	return sum * r.cutoff
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// resampledFrames returns the number of frames after resampling.
+func resampledFrames(frames int64, from, to int) int64 {
+	return (frames*int64(to) + int64(from) - 1) / int64(from)
+}
@@ -0,0 +1,81 @@
+package wav
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestResampledFrames(t *testing.T) {
+	tests := []struct {
+		frames   int64
+		from, to int
+		expected int64
+	}{
+		{48000, 48000, 16000, 16000},
+		{48001, 48000, 16000, 16001},
+		{44100, 44100, 16000, 16000},
+		{8000, 8000, 16000, 16000},
+		{1, 44100, 16000, 1},
+		{0, 44100, 16000, 0},
+	}
+
+	for _, tt := range tests {
+		if actual := resampledFrames(tt.frames, tt.from, tt.to); actual != tt.expected {
+			t.Errorf("resampledFrames(%v, %v, %v) = %v, want %v", tt.frames, tt.from, tt.to, actual, tt.expected)
+		}
+	}
+}
+
+func TestResampler(t *testing.T) {
+	tests := []struct {
+		from, to int
+		freq     float64 // Hz of input sine
+		gain     float64 // expected gain
+	}{
+		{48000, 16000, 440, 1},
+		{44100, 16000, 1000, 1},
+		{8000, 16000, 440, 1},
+		{16000, 16000, 440, 1},
+		{48000, 16000, 12000, 0}, // above Nyquist of the output: filtered
+	}
+
+	for _, tt := range tests {
+		frames := tt.from // 1s
+		r := newResampler(tt.from, tt.to)
+
+		var out []float64
+		for i := 0; i < frames; i++ {
+			out = r.push(math.Sin(2*math.Pi*tt.freq*float64(i)/float64(tt.from)), out)
+		}
+		out = r.flush(out)
+
+		if n := int64(len(out)); n != resampledFrames(int64(frames), tt.from, tt.to) {
+			t.Errorf("resample(%v->%v) = %v samples, want %v", tt.from, tt.to, n, resampledFrames(int64(frames), tt.from, tt.to))
+		}
+
+		// Compare with the ideal output away from the edges.
+
+		var sum float64
+		for i := len(out) / 4; i < 3*len(out)/4; i++ {
+			expected := tt.gain * math.Sin(2*math.Pi*tt.freq*float64(i)/float64(tt.to))
+			sum += (out[i] - expected) * (out[i] - expected)
+		}
+		if rms := math.Sqrt(sum / float64(len(out)/2)); rms > 0.01 {
+			t.Errorf("resample(%v->%v, %vHz) has RMS error %v, want < 0.01", tt.from, tt.to, tt.freq, rms)
+		}
+	}
+}
+
+func TestConvertResample(t *testing.T) {
+	in := writePCM(t, 48000, loud(4800))
+
+	out := filepath.Join(t.TempDir(), "out.wav")
+	if err := Convert(in, out, ConvertOptions{SampleRate: 16000}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	h, samples := readPCM(t, out)
+	if h.SampleRate != 16000 || len(samples) != 1600 {
+		t.Errorf("Convert = %v with %v samples, want 16000Hz with 1600 samples", h, len(samples))
+	}
+}
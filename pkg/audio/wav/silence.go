@@ -0,0 +1,220 @@
+package wav
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Segment is an interval of audio.
+type Segment struct {
+	Start, End time.Duration
+}
+
+// SilenceOptions define which silences to remove.
+type SilenceOptions struct {
+	// Threshold is the level in dBFS below which audio is silent, such as -40.
+	Threshold float64
+	// MinDuration is the minimum duration of silence to remove.
+	MinDuration time.Duration
+	// Padding is the duration of silence kept next to non-silent audio.
+	Padding time.Duration
+}
+
+// window is the duration over which the audio level is measured.
+const window = 10 * time.Millisecond
+
+// Voiced returns the segments of the wav file to keep, if silences of at least
+// the minimum duration are removed. Silence is detected by the RMS level of
+// all channels over short windows. The format must be supported by Convert.
+func Voiced(filename string, opts SilenceOptions) ([]Segment, error) {
+	h, silent, err := levels(filename, opts.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep everything but long silent runs, minus padding.
+
+	min := int(opts.MinDuration / window)
+	pad := int(opts.Padding / window)
+
+	var ret []Segment
+	start := 0
+	for i := 0; i < len(silent); {
+		if !silent[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(silent) && silent[j] {
+			j++
+		}
+		if from, to := i+pad, j-pad; j-i >= min && from < to {
+			if from > start {
+				ret = append(ret, Segment{Start: time.Duration(start) * window, End: time.Duration(from) * window})
+			}
+			start = to
+		}
+		i = j
+	}
+	if start < len(silent) {
+		ret = append(ret, Segment{Start: time.Duration(start) * window, End: h.Duration()})
+	}
+	return ret, nil
+}
+
+// Chunks splits the wav file into consecutive segments of at most the given
+// duration. Each split is placed in the middle of the longest silence in the
+// second half of the segment, if any, so that words are not cut in half.
+func Chunks(filename string, max time.Duration, threshold float64) ([]Segment, error) {
+	h, silent, err := levels(filename, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(max / window)
+	if size < 2 {
+		return nil, fmt.Errorf("chunk duration too short: %v", max)
+	}
+
+	var ret []Segment
+	start := 0
+	for len(silent)-start > size {
+		at := start + size // hard split, if no silence
+
+		best := 0
+		for i := start + size/2; i < start+size; {
+			if !silent[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < start+size && silent[j] {
+				j++
+			}
+			if j-i > best {
+				best, at = j-i, (i+j)/2
+			}
+			i = j
+		}
+
+		ret = append(ret, Segment{Start: time.Duration(start) * window, End: time.Duration(at) * window})
+		start = at
+	}
+	return append(ret, Segment{Start: time.Duration(start) * window, End: h.Duration()}), nil
+}
+
+// levels returns the header of the wav file and whether each window of the
+// audio is below the threshold in dBFS.
+func levels(filename string, threshold float64) (*Header, []bool, error) {
+	fd, h, err := open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fd.Close()
+
+	r := bufio.NewReader(fd)
+	if !Supported(h) || h.Channels < 1 {
+		return nil, nil, fmt.Errorf("unsupported format for silence detection: %v", h)
+	}
+
+	width := h.BitsPerSample / 8
+	size := int64(h.SampleRate) * int64(window) / int64(time.Second)
+	if size < 1 {
+		return nil, nil, fmt.Errorf("invalid sample rate for silence detection: %v", h)
+	}
+	frames := h.DataSize / int64(h.BlockAlign())
+	level := math.Pow(10, threshold/20)
+
+	var silent []bool
+	frame := make([]byte, h.BlockAlign())
+	for i := int64(0); i < frames; i += size {
+		n := size
+		if i+n > frames {
+			n = frames - i
+		}
+
+		sum := 0.0
+		for j := int64(0); j < n; j++ {
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return nil, nil, fmt.Errorf("truncated audio data: %v", err)
+			}
+			for c := 0; c < h.Channels; c++ {
+				v := decode(h, frame[c*width:(c+1)*width])
+				sum += v * v
+			}
+		}
+		rms := math.Sqrt(sum / float64(n*int64(h.Channels)))
+		silent = append(silent, rms < level)
+	}
+	return h, silent, nil
+}
+
+// Cut writes the given segments of a wav file, in order, to a new wav file in
+// the same format.
+func Cut(in, out string, segments []Segment) error {
+	src, h, err := open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	align := int64(h.BlockAlign())
+	frames := h.DataSize / align
+	offset := func(d time.Duration) int64 {
+		f := int64(d) * int64(h.SampleRate) / int64(time.Second)
+		if f > frames {
+			return frames
+		}
+		return f
+	}
+
+	target := *h
+	target.DataSize = 0
+	for _, s := range segments {
+		target.DataSize += (offset(s.End) - offset(s.Start)) * align
+	}
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(dst)
+
+	if err := WriteHeader(w, &target); err != nil {
+		dst.Close()
+		return err
+	}
+	for _, s := range segments {
+		from, to := offset(s.Start), offset(s.End)
+		if _, err := io.Copy(w, io.NewSectionReader(src, h.DataOffset+from*align, (to-from)*align)); err != nil {
+			dst.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Offset maps an offset in audio cut to the given segments back to the offset
+// in the original audio.
+func Offset(segments []Segment, d time.Duration) time.Duration {
+	var pos time.Duration
+	for _, s := range segments {
+		if d < pos+s.End-s.Start {
+			return s.Start + d - pos
+		}
+		pos += s.End - s.Start
+	}
+	if len(segments) > 0 {
+		return segments[len(segments)-1].End + d - pos
+	}
+	return d
+}
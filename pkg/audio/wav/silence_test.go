@@ -0,0 +1,131 @@
+package wav
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVoiced(t *testing.T) {
+	// 1s loud, 1s silent, 1s loud at 1kHz.
+	filename := writePCM(t, 1000, loud(1000), make([]int16, 1000), loud(1000))
+
+	tests := []struct {
+		opts     SilenceOptions
+		expected []Segment
+	}{
+		{
+			SilenceOptions{Threshold: -40, MinDuration: 500 * time.Millisecond, Padding: 100 * time.Millisecond},
+			[]Segment{{0, 1100 * time.Millisecond}, {1900 * time.Millisecond, 3 * time.Second}},
+		},
+		{
+			SilenceOptions{Threshold: -40, MinDuration: 500 * time.Millisecond},
+			[]Segment{{0, time.Second}, {2 * time.Second, 3 * time.Second}},
+		},
+		{
+			SilenceOptions{Threshold: -40, MinDuration: 2 * time.Second},
+			[]Segment{{0, 3 * time.Second}},
+		},
+		{
+			SilenceOptions{Threshold: -40, MinDuration: 500 * time.Millisecond, Padding: 600 * time.Millisecond},
+			[]Segment{{0, 3 * time.Second}},
+		},
+	}
+
+	for _, tt := range tests {
+		actual, err := Voiced(filename, tt.opts)
+		if err != nil {
+			t.Fatalf("Voiced(%+v) failed: %v", tt.opts, err)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Voiced(%+v) = %v, want %v", tt.opts, actual, tt.expected)
+		}
+	}
+}
+
+func TestChunks(t *testing.T) {
+	filename := writePCM(t, 1000, loud(1000), make([]int16, 1000), loud(1000))
+
+	tests := []struct {
+		max      time.Duration
+		expected []Segment
+	}{
+		{4 * time.Second, []Segment{{0, 3 * time.Second}}},
+		{2 * time.Second, []Segment{{0, 1500 * time.Millisecond}, {1500 * time.Millisecond, 3 * time.Second}}},
+		{time.Second, []Segment{{0, time.Second}, {time.Second, 1750 * time.Millisecond}, {1750 * time.Millisecond, 2750 * time.Millisecond}, {2750 * time.Millisecond, 3 * time.Second}}},
+	}
+
+	for _, tt := range tests {
+		actual, err := Chunks(filename, tt.max, -40)
+		if err != nil {
+			t.Fatalf("Chunks(%v) failed: %v", tt.max, err)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Chunks(%v) = %v, want %v", tt.max, actual, tt.expected)
+		}
+	}
+
+	if _, err := Chunks(filename, 10*time.Millisecond, -40); err == nil {
+		t.Errorf("Chunks(10ms) succeeded, want error")
+	}
+}
+
+func TestLevelsInvalidRate(t *testing.T) {
+	for _, rate := range []int{0, 50, 99} {
+		filename := writePCM(t, rate, loud(100))
+
+		if _, err := Voiced(filename, SilenceOptions{Threshold: -40, MinDuration: time.Second}); err == nil {
+			t.Errorf("Voiced(%vHz) succeeded, want error", rate)
+		}
+		if _, err := Chunks(filename, time.Minute, -40); err == nil {
+			t.Errorf("Chunks(%vHz) succeeded, want error", rate)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	segments := []Segment{{time.Second, 2 * time.Second}, {5 * time.Second, 7 * time.Second}}
+
+	tests := []struct {
+		d, expected time.Duration
+	}{
+		{0, time.Second},
+		{500 * time.Millisecond, 1500 * time.Millisecond},
+		{time.Second, 5 * time.Second},
+		{2500 * time.Millisecond, 6500 * time.Millisecond},
+		{4 * time.Second, 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if actual := Offset(segments, tt.d); actual != tt.expected {
+			t.Errorf("Offset(%v) = %v, want %v", tt.d, actual, tt.expected)
+		}
+	}
+}
+
+// loud returns n samples of a loud square wave.
+func loud(n int) []int16 {
+	ret := make([]int16, n)
+	for i := range ret {
+		ret[i] = 16000
+		if i%2 == 1 {
+			ret[i] = -16000
+		}
+	}
+	return ret
+}
+
+// writePCM writes the concatenated samples as a mono 16-bit wav file at the
+// given sample rate.
+func writePCM(t *testing.T, rate int, parts ...[]int16) string {
+	t.Helper()
+
+	var data []byte
+	for _, p := range parts {
+		for _, v := range p {
+			data = binary.LittleEndian.AppendUint16(data, uint16(v))
+		}
+	}
+	return writeWAV(t, &Header{Format: FormatPCM, Channels: 1, SampleRate: rate, BitsPerSample: 16}, data)
+}
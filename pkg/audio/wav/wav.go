@@ -0,0 +1,197 @@
+// Package wav contains utilities for reading WAV (RIFF) audio files.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+// Format codes of WAV files.
+const (
+	FormatPCM        = 0x0001
+	FormatFloat      = 0x0003
+	FormatALaw       = 0x0006
+	FormatMuLaw      = 0x0007
+	FormatExtensible = 0xFFFE
+)
+
+// formats are the names of common format codes, including unsupported ones.
+var formats = map[uint16]string{
+	FormatPCM:   "PCM",
+	0x0002:      "MS ADPCM",
+	FormatFloat: "float",
+	FormatALaw:  "A-law",
+	FormatMuLaw: "µ-law",
+	0x0011:      "IMA ADPCM",
+	0x0031:      "GSM 6.10",
+	0x0055:      "MP3",
+}
+
+// maxFmtSize is the maximum size of a fmt chunk, which is read into memory. It
+// is at most 40 bytes in practice.
+const maxFmtSize = 64 << 10
+
+// ErrNotWAV indicates that the data is not a WAV file.
+var ErrNotWAV = errors.New("not a WAV file")
+
+// Header is the format information of a WAV file.
+type Header struct {
+	// Format is the format code, such as FormatPCM.
+	Format uint16
+	// Channels is the number of interleaved channels.
+	Channels int
+	// SampleRate is the number of samples per second.
+	SampleRate int
+	// BitsPerSample is the bit depth, such as 16.
+	BitsPerSample int
+	// DataOffset is the offset of the audio data in the file.
+	DataOffset int64
+	// DataSize is the size of the audio data in bytes.
+	DataSize int64
+	// Repairs are the deviations from a canonical header that were repaired
+	// or resolved when reading the file, such as a wrong data size. Such files
+	// should be rewritten before upload.
+	Repairs []string
+}
+
+// BlockAlign returns the size in bytes of a frame, i.e., a sample for each channel.
+func (h *Header) BlockAlign() int {
+	return h.Channels * ((h.BitsPerSample + 7) / 8)
+}
+
+// Duration returns the duration of the audio data.
+func (h *Header) Duration() time.Duration {
+	if h.SampleRate == 0 || h.BlockAlign() == 0 {
+		return 0
+	}
+	frames := h.DataSize / int64(h.BlockAlign())
+	return time.Duration(frames) * time.Second / time.Duration(h.SampleRate)
+}
+
+func (h *Header) String() string {
+	format := fmt.Sprintf("0x%04x", h.Format)
+	if name, ok := formats[h.Format]; ok {
+		format = name
+	}
+	return fmt.Sprintf("wav[format=%v, channels=%v, rate=%vHz, bits=%v, duration=%v]", format, h.Channels, h.SampleRate, h.BitsPerSample, h.Duration())
+}
+
+// ReadFile reads the header of the given WAV file. The data size is repaired,
+// if missing or inconsistent with the file size.
+func ReadFile(filename string) (*Header, error) {
+	fd, h, err := open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fd.Close()
+	return h, nil
+}
+
+// open opens the given WAV file and reads its header. The data size is
+// repaired, if needed. The file is positioned at the start of the audio data.
+func open(filename string) (*os.File, *Header, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := ReadHeader(fd)
+	if err != nil {
+		fd.Close()
+		return nil, nil, err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, nil, err
+	}
+	h.Repair(fi.Size())
+	return fd, h, nil
+}
+
+// Repair fixes the data size against the given file size, such as of a header
+// read from a remote file. Recorders that stream to disk may leave the size
+// unset or wrong if interrupted.
+func (h *Header) Repair(size int64) {
+	rest := size - h.DataOffset
+	switch {
+	case (h.DataSize == 0 || h.DataSize == math.MaxUint32) && rest > 0:
+		h.DataSize = rest
+		h.Repairs = append(h.Repairs, "data size not set")
+	case h.DataSize > rest:
+		h.DataSize = rest
+		h.Repairs = append(h.Repairs, "data size exceeds file size")
+	}
+	if align := int64(h.BlockAlign()); align > 0 && h.DataSize%align != 0 {
+		h.DataSize -= h.DataSize % align
+		h.Repairs = append(h.Repairs, "partial frame at end")
+	}
+}
+
+// ReadHeader reads the header of a WAV file from the given reader. The reader
+// is positioned at the start of the audio data, if successful.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, ErrNotWAV
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, ErrNotWAV
+	}
+
+	var h *Header
+	offset := int64(len(riff))
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("invalid WAV file: no data chunk")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		offset += int64(len(chunk))
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > maxFmtSize {
+				return nil, fmt.Errorf("invalid WAV file: invalid fmt chunk size: %v", size)
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, fmt.Errorf("invalid WAV file: truncated fmt chunk: %v", err)
+			}
+			h = &Header{
+				Format:        binary.LittleEndian.Uint16(buf[0:2]),
+				Channels:      int(binary.LittleEndian.Uint16(buf[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(buf[4:8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(buf[14:16])),
+			}
+			if h.Format == FormatExtensible {
+				// The actual format code is the start of the SubFormat GUID.
+				if size < 40 {
+					return nil, fmt.Errorf("invalid WAV file: extensible fmt chunk too small: %v", size)
+				}
+				h.Format = binary.LittleEndian.Uint16(buf[24:26])
+				h.Repairs = append(h.Repairs, "extensible format header")
+			}
+
+		case "data":
+			if h == nil {
+				return nil, fmt.Errorf("invalid WAV file: data chunk before fmt chunk")
+			}
+			h.DataOffset = offset
+			h.DataSize = size
+			return h, nil
+
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, size+size%2); err != nil {
+				return nil, fmt.Errorf("invalid WAV file: truncated %q chunk: %v", id, err)
+			}
+		}
+		offset += size + size%2
+	}
+}
@@ -0,0 +1,110 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestReadHeader(t *testing.T) {
+	pcm := fmtChunk(FormatPCM, 2, 44100, 16, 16)
+	extensible := fmtChunk(FormatExtensible, 1, 48000, 24, 40)
+	binary.LittleEndian.PutUint16(extensible[8+24:], FormatFloat)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *Header
+	}{
+		{"pcm", riff(pcm, chunk("data", 400)), &Header{Format: FormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 16, DataOffset: 44, DataSize: 400}},
+		{"list before data", riff(pcm, chunk("LIST", 3), chunk("data", 400)), &Header{Format: FormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 16, DataOffset: 56, DataSize: 400}},
+		{"extensible", riff(extensible, chunk("data", 300)), &Header{Format: FormatFloat, Channels: 1, SampleRate: 48000, BitsPerSample: 24, DataOffset: 68, DataSize: 300, Repairs: []string{"extensible format header"}}},
+		{"not riff", []byte("RIFX\x00\x00\x00\x00WAVE"), nil},
+		{"data before fmt", riff(chunk("data", 4), pcm), nil},
+		{"no data", riff(pcm), nil},
+		{"small fmt", riff(fmtChunk(FormatPCM, 1, 8000, 16, 14), chunk("data", 4)), nil},
+		{"huge fmt", riff(chunk("fmt ", 0xfffffff0)), nil},
+		{"small extensible", riff(fmtChunk(FormatExtensible, 1, 8000, 16, 18), chunk("data", 4)), nil},
+	}
+
+	for _, tt := range tests {
+		actual, err := ReadHeader(bytes.NewReader(tt.data))
+		if tt.expected == nil {
+			if err == nil {
+				t.Errorf("ReadHeader(%v) = %v, want error", tt.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ReadHeader(%v) failed: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ReadHeader(%v) = %+v, want %+v", tt.name, actual, tt.expected)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		size, file int64
+		expected   int64
+		repairs    int
+	}{
+		{400, 444, 400, 0},
+		{0, 444, 400, 1},
+		{0xffffffff, 444, 400, 1},
+		{800, 444, 400, 1},
+		{400, 1000, 400, 0},
+		{0, 443, 396, 2},
+		{0, 44, 0, 0},
+	}
+
+	for _, tt := range tests {
+		h := &Header{Format: FormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 16, DataOffset: 44, DataSize: tt.size}
+		h.Repair(tt.file)
+		if h.DataSize != tt.expected || len(h.Repairs) != tt.repairs {
+			t.Errorf("Repair(%v, %v) = %v %v, want %v with %v repairs", tt.size, tt.file, h.DataSize, h.Repairs, tt.expected, tt.repairs)
+		}
+	}
+}
+
+// riff returns a RIFF/WAVE file with the given chunks.
+func riff(chunks ...[]byte) []byte {
+	body := bytes.Join(chunks, nil)
+	ret := append([]byte("RIFF"), le32(uint32(4+len(body)))...)
+	ret = append(ret, "WAVE"...)
+	return append(ret, body...)
+}
+
+// chunk returns a chunk header of the given size with zero data, except for
+// the data chunk, whose data is omitted.
+func chunk(id string, size uint32) []byte {
+	ret := append([]byte(id), le32(size)...)
+	if id == "data" || size > 1<<16 {
+		return ret
+	}
+	return append(ret, make([]byte, size+size%2)...)
+}
+
+// fmtChunk returns a fmt chunk of the given size and format.
+func fmtChunk(format uint16, channels, rate, bits int, size uint32) []byte {
+	ret := chunk("fmt ", size)
+	if size < 16 {
+		return ret
+	}
+	binary.LittleEndian.PutUint16(ret[8:], format)
+	binary.LittleEndian.PutUint16(ret[10:], uint16(channels))
+	binary.LittleEndian.PutUint32(ret[12:], uint32(rate))
+	binary.LittleEndian.PutUint32(ret[16:], uint32(rate*channels*bits/8))
+	binary.LittleEndian.PutUint16(ret[20:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(ret[22:], uint16(bits))
+	return ret
+}
+
+func le32(v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return buf[:]
+}
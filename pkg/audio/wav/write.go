@@ -0,0 +1,307 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// WriteHeader writes a canonical 44-byte PCM wav header for the given format
+// and data size.
+func WriteHeader(w io.Writer, h *Header) error {
+	var buf [44]byte
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+h.DataSize))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], h.Format)
+	binary.LittleEndian.PutUint16(buf[22:24], uint16(h.Channels))
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(h.SampleRate))
+	binary.LittleEndian.PutUint32(buf[28:32], uint32(h.SampleRate*h.BlockAlign()))
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(h.BlockAlign()))
+	binary.LittleEndian.PutUint16(buf[34:36], uint16(h.BitsPerSample))
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(h.DataSize))
+
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// Wrap writes the headerless PCM data of the given file, such as audio dumped
+// from embedded devices, as a wav file in the given format. The data size is
+// taken from the file. Any trailing partial frame is dropped.
+func Wrap(in, out string, h *Header) error {
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if h.BlockAlign() == 0 {
+		return fmt.Errorf("invalid format: %v", h)
+	}
+	target := *h
+	target.DataSize = fi.Size() / int64(h.BlockAlign()) * int64(h.BlockAlign())
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(dst)
+
+	if err := WriteHeader(w, &target); err != nil {
+		dst.Close()
+		return err
+	}
+	if _, err := io.CopyN(w, src, target.DataSize); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Supported returns true iff the format can be converted by Convert, i.e., it
+// is 8, 16, 24 or 32-bit PCM, 32 or 64-bit float or 8-bit A-law or µ-law.
+func Supported(h *Header) bool {
+	switch h.Format {
+	case FormatALaw, FormatMuLaw:
+		return h.BitsPerSample == 8
+	case FormatPCM:
+		return h.BitsPerSample == 8 || h.BitsPerSample == 16 || h.BitsPerSample == 24 || h.BitsPerSample == 32
+	case FormatFloat:
+		return h.BitsPerSample == 32 || h.BitsPerSample == 64
+	default:
+		return false
+	}
+}
+
+// ConvertOptions are options for Convert.
+type ConvertOptions struct {
+	// Mono indicates that the (selected) channels are downmixed to mono by
+	// averaging the channels of each frame.
+	Mono bool
+	// SampleRate is the target sample rate in Hz. If zero, the rate is kept.
+	SampleRate int
+	// Channels are the channels to keep, 1-based and in order. If empty, all
+	// channels are kept.
+	Channels []int
+}
+
+// Convert converts a wav file to 16-bit PCM, optionally with a selection of
+// its channels, downmixed to mono or resampled. Higher bit depths and float
+// samples are reduced with TPDF dithering. It writes the result to a new wav
+// file.
+func Convert(in, out string, opts ConvertOptions) error {
+	src, h, err := open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r := bufio.NewReader(src)
+	if !Supported(h) || h.Channels < 1 {
+		return fmt.Errorf("unsupported format for conversion: %v", h)
+	}
+
+	selected := opts.Channels
+	if len(selected) == 0 {
+		for c := 1; c <= h.Channels; c++ {
+			selected = append(selected, c)
+		}
+	}
+	for _, c := range selected {
+		if c < 1 || c > h.Channels {
+			return fmt.Errorf("invalid channel %v: audio has %v channels", c, h.Channels)
+		}
+	}
+
+	channels := len(selected)
+	if opts.Mono {
+		channels = 1
+	}
+	rate := opts.SampleRate
+	if rate <= 0 {
+		rate = h.SampleRate
+	}
+	resample := rate != h.SampleRate
+	dither := h.Format == FormatFloat || h.BitsPerSample > 16 || resample
+	width := h.BitsPerSample / 8
+
+	frames := h.DataSize / int64(h.BlockAlign())
+	target := &Header{
+		Format:        FormatPCM,
+		Channels:      channels,
+		SampleRate:    rate,
+		BitsPerSample: 16,
+	}
+	target.DataSize = resampledFrames(frames, h.SampleRate, rate) * int64(target.BlockAlign())
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(dst)
+
+	if err := WriteHeader(w, target); err != nil {
+		dst.Close()
+		return err
+	}
+
+	var rs []*resampler
+	if resample {
+		for c := 0; c < channels; c++ {
+			rs = append(rs, newResampler(h.SampleRate, rate))
+		}
+	}
+	pending := make([][]float64, channels)
+
+	frame := make([]byte, h.BlockAlign())
+	decoded := make([]float64, h.Channels)
+	samples := make([]float64, len(selected))
+	buf := make([]byte, target.BlockAlign())
+	for i := int64(0); i < frames; i++ {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			dst.Close()
+			return fmt.Errorf("truncated audio data: %v", err)
+		}
+		for c := range decoded {
+			decoded[c] = decode(h, frame[c*width:(c+1)*width])
+		}
+		for j, c := range selected {
+			samples[j] = decoded[c-1]
+		}
+		if opts.Mono {
+			sum := 0.0
+			for _, v := range samples {
+				sum += v
+			}
+			samples[0] = sum / float64(len(samples))
+		}
+		for c := 0; c < channels; c++ {
+			if resample {
+				pending[c] = rs[c].push(samples[c], pending[c][:0])
+			} else {
+				pending[c] = append(pending[c][:0], samples[c])
+			}
+		}
+		if err := writeFrames(w, buf, pending, dither); err != nil {
+			dst.Close()
+			return err
+		}
+	}
+	if resample {
+		for c := 0; c < channels; c++ {
+			pending[c] = rs[c].flush(pending[c][:0])
+		}
+		if err := writeFrames(w, buf, pending, dither); err != nil {
+			dst.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// writeFrames writes the pending samples of each channel as interleaved 16-bit
+// frames. All channels have the same number of pending samples.
+func writeFrames(w io.Writer, buf []byte, pending [][]float64, dither bool) error {
+	for i := range pending[0] {
+		for c := range pending {
+			binary.LittleEndian.PutUint16(buf[2*c:], uint16(encode16(pending[c][i], dither)))
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode returns the sample as a value in [-1;1].
+func decode(h *Header, b []byte) float64 {
+	switch {
+	case h.Format == FormatMuLaw:
+		return float64(mulaw(b[0])) / (1 << 15)
+	case h.Format == FormatALaw:
+		return float64(alaw(b[0])) / (1 << 15)
+	case h.Format == FormatFloat && h.BitsPerSample == 32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case h.Format == FormatFloat:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case h.BitsPerSample == 8:
+		return float64(int(b[0])-128) / (1 << 7) // unsigned
+	case h.BitsPerSample == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case h.BitsPerSample == 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8 // sign-extend
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// mulaw decodes a G.711 µ-law sample.
+func mulaw(u byte) int16 {
+	u = ^u
+	exponent := (u >> 4) & 0x07
+	mantissa := int(u & 0x0f)
+
+	v := ((mantissa << 3) + 0x84) << exponent
+	v -= 0x84
+	if u&0x80 != 0 {
+		return int16(-v)
+	}
+	return int16(v)
+}
+
+// alaw decodes a G.711 A-law sample.
+func alaw(a byte) int16 {
+	a ^= 0x55
+	v := int(a&0x0f) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		v += 8
+	case 1:
+		v += 0x108
+	default:
+		v += 0x108
+		v <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(v)
+	}
+	return int16(-v)
+}
+
+// encode16 returns the value in [-1;1] as a 16-bit sample, optionally with
+// triangular (TPDF) dither of 1 LSB.
+func encode16(v float64, dither bool) int16 {
+	v *= 1 << 15
+	if dither {
+		v += rand.Float64() - rand.Float64()
+	}
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
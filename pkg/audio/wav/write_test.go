@@ -0,0 +1,201 @@
+package wav
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	f32 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(f32, math.Float32bits(-0.25))
+	f64 := make([]byte, 8)
+	binary.LittleEndian.PutUint64(f64, math.Float64bits(0.75))
+
+	tests := []struct {
+		format   uint16
+		bits     int
+		data     []byte
+		expected float64
+	}{
+		{FormatPCM, 8, []byte{128}, 0},
+		{FormatPCM, 8, []byte{0}, -1},
+		{FormatPCM, 8, []byte{192}, 0.5},
+		{FormatPCM, 16, []byte{0x00, 0x40}, 0.5},
+		{FormatPCM, 16, []byte{0x00, 0x80}, -1},
+		{FormatPCM, 24, []byte{0x00, 0x00, 0x40}, 0.5},
+		{FormatPCM, 24, []byte{0x00, 0x00, 0xc0}, -0.5},
+		{FormatPCM, 32, []byte{0x00, 0x00, 0x00, 0xc0}, -0.5},
+		{FormatFloat, 32, f32, -0.25},
+		{FormatFloat, 64, f64, 0.75},
+	}
+
+	for _, tt := range tests {
+		h := &Header{Format: tt.format, Channels: 1, BitsPerSample: tt.bits}
+		if actual := decode(h, tt.data); actual != tt.expected {
+			t.Errorf("decode(%v, %v bits, %x) = %v, want %v", tt.format, tt.bits, tt.data, actual, tt.expected)
+		}
+	}
+}
+
+func TestMulaw(t *testing.T) {
+	tests := []struct {
+		u        byte
+		expected int16
+	}{
+		{0xff, 0},
+		{0x7f, 0},
+		{0x80, 32124},
+		{0x00, -32124},
+		{0xfe, 8},
+		{0x7e, -8},
+		{0xef, 132},
+	}
+
+	for _, tt := range tests {
+		if actual := mulaw(tt.u); actual != tt.expected {
+			t.Errorf("mulaw(%#x) = %v, want %v", tt.u, actual, tt.expected)
+		}
+	}
+}
+
+func TestAlaw(t *testing.T) {
+	tests := []struct {
+		a        byte
+		expected int16
+	}{
+		{0xd5, 8},
+		{0x55, -8},
+		{0xaa, 32256},
+		{0x2a, -32256},
+		{0xc5, 264},
+		{0xe5, 1056},
+	}
+
+	for _, tt := range tests {
+		if actual := alaw(tt.a); actual != tt.expected {
+			t.Errorf("alaw(%#x) = %v, want %v", tt.a, actual, tt.expected)
+		}
+	}
+}
+
+func TestConvertMulaw(t *testing.T) {
+	in := writeWAV(t, &Header{Format: FormatMuLaw, Channels: 1, SampleRate: 8000, BitsPerSample: 8}, []byte{0xff, 0x80, 0x00})
+
+	out := filepath.Join(t.TempDir(), "out.wav")
+	if err := Convert(in, out, ConvertOptions{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	_, samples := readPCM(t, out)
+	if len(samples) != 3 || samples[0] != 0 || samples[1] != 32124 || samples[2] != -32124 {
+		t.Errorf("Convert = %v, want [0 32124 -32124]", samples)
+	}
+}
+
+func TestEncode16(t *testing.T) {
+	tests := []struct {
+		v        float64
+		expected int16
+	}{
+		{0, 0},
+		{0.5, 16384},
+		{-0.5, -16384},
+		{-1, math.MinInt16},
+		{1, math.MaxInt16},
+		{2, math.MaxInt16},
+		{-2, math.MinInt16},
+	}
+
+	for _, tt := range tests {
+		if actual := encode16(tt.v, false); actual != tt.expected {
+			t.Errorf("encode16(%v) = %v, want %v", tt.v, actual, tt.expected)
+		}
+		for i := 0; i < 100; i++ {
+			if actual := encode16(tt.v, true); math.Abs(float64(actual)-float64(tt.expected)) > 2 {
+				t.Errorf("encode16(%v, dither) = %v, want %v +/- 2", tt.v, actual, tt.expected)
+			}
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// Stereo 24-bit audio with 0.5 on the left and -0.25 on the right channel.
+	var data []byte
+	for i := 0; i < 100; i++ {
+		data = append(data, 0x00, 0x00, 0x40, 0x00, 0x00, 0xe0)
+	}
+	in := writeWAV(t, &Header{Format: FormatPCM, Channels: 2, SampleRate: 8000, BitsPerSample: 24}, data)
+
+	tests := []struct {
+		opts     ConvertOptions
+		expected []float64 // first frame
+	}{
+		{ConvertOptions{}, []float64{0.5, -0.25}},
+		{ConvertOptions{Mono: true}, []float64{0.125}},
+		{ConvertOptions{Channels: []int{2}}, []float64{-0.25}},
+		{ConvertOptions{Channels: []int{2, 1}}, []float64{-0.25, 0.5}},
+	}
+
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.wav")
+		if err := Convert(in, out, tt.opts); err != nil {
+			t.Fatalf("Convert(%+v) failed: %v", tt.opts, err)
+		}
+
+		h, samples := readPCM(t, out)
+		if h.BitsPerSample != 16 || h.Channels != len(tt.expected) || h.SampleRate != 8000 || len(samples) != 100*len(tt.expected) {
+			t.Fatalf("Convert(%+v) = %v with %v samples, want 16-bit with %v channels", tt.opts, h, len(samples), len(tt.expected))
+		}
+		for c, v := range tt.expected {
+			if actual := float64(samples[c]) / (1 << 15); math.Abs(actual-v) > 2.0/(1<<15) {
+				t.Errorf("Convert(%+v) channel %v = %v, want %v", tt.opts, c+1, actual, v)
+			}
+		}
+	}
+
+	if err := Convert(in, filepath.Join(t.TempDir(), "out.wav"), ConvertOptions{Channels: []int{3}}); err == nil {
+		t.Errorf("Convert(channel 3) succeeded, want error")
+	}
+}
+
+// writeWAV writes a wav file with the given format and audio data.
+func writeWAV(t *testing.T, h *Header, data []byte) string {
+	t.Helper()
+
+	target := *h
+	target.DataSize = int64(len(data))
+
+	filename := filepath.Join(t.TempDir(), "test.wav")
+	fd, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if err := WriteHeader(fd, &target); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// readPCM reads a 16-bit wav file.
+func readPCM(t *testing.T, filename string) (*Header, []int16) {
+	t.Helper()
+
+	fd, h, err := open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	samples := make([]int16, h.DataSize/2)
+	if err := binary.Read(fd, binary.LittleEndian, samples); err != nil {
+		t.Fatal(err)
+	}
+	return h, samples
+}
@@ -0,0 +1,142 @@
+// Package cache is a local file cache for converted audio files and API
+// responses. Entries are keyed by the content of the source file and the
+// conversion or recognition settings, so that re-running a batch does not redo
+// expensive conversions or transcriptions.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Cache is a directory of cached files.
+type Cache struct {
+	dir string
+}
+
+// New returns a cache in the given directory. The directory is created, if
+// it does not exist.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %v: %v", dir, err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key returns the cache key for the given source file and conversion settings.
+func Key(filename string, settings ...string) (string, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fd); err != nil {
+		return "", fmt.Errorf("failed to hash %v: %v", filename, err)
+	}
+	for _, s := range settings {
+		fmt.Fprintf(h, "\x00%v", s)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// KeyOf returns the cache key for content with the given hash, such as of a
+// remote object, and settings.
+func KeyOf(hash string, settings ...string) string {
+	h := sha256.New()
+	fmt.Fprint(h, hash)
+	for _, s := range settings {
+		fmt.Fprintf(h, "\x00%v", s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup returns the path of the cached file with the given key and extension,
+// if present.
+func (c *Cache) Lookup(key, ext string) (string, bool) {
+	path := c.path(key, ext)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// Store moves the given file into the cache under the given key and extension.
+// It returns the path of the cached file.
+func (c *Cache) Store(key, ext, filename string) (string, error) {
+	tmp, err := ioutil.TempFile(c.dir, key+".tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+
+	if err := move(filename, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store %v in cache: %v", filename, err)
+	}
+
+	path := c.path(key, ext)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store %v in cache: %v", filename, err)
+	}
+	return path, nil
+}
+
+// Put stores the given data in the cache under the given key and extension.
+func (c *Cache) Put(key, ext string, data []byte) error {
+	tmp, err := ioutil.TempFile(c.dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key, ext)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store %v in cache: %v", key, err)
+	}
+	return nil
+}
+
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.dir, key+ext)
+}
+
+// move moves a file, falling back to copying across file systems.
+func move(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
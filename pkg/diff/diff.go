@@ -0,0 +1,180 @@
+// Package diff compares transcripts of the same audio word by word, such as
+// from different providers or model settings, and renders the differences.
+package diff
+
+import "strings"
+
+// Op is a diff operation.
+type Op int
+
+const (
+	// Equal indicates words present in both transcripts.
+	Equal Op = iota
+	// Delete indicates words present only in the first transcript.
+	Delete
+	// Insert indicates words present only in the second transcript.
+	Insert
+)
+
+// Edit is a run of words with the same operation.
+type Edit struct {
+	Op    Op
+	Words []string
+}
+
+// Stats are summary statistics of a diff, relative to the first transcript.
+type Stats struct {
+	// Words is the number of words in the first transcript.
+	Words int
+	// Substitutions, Deletions and Insertions are the number of differing words.
+	// A substitution is a deletion directly followed by an insertion.
+	Substitutions, Deletions, Insertions int
+}
+
+// Rate returns the word difference rate, i.e., the number of differing words
+// relative to the first transcript. If the first transcript is a reference
+// transcript, it is the word error rate (WER).
+func (s Stats) Rate() float64 {
+	if s.Words == 0 {
+		return 0
+	}
+	return float64(s.Substitutions+s.Deletions+s.Insertions) / float64(s.Words)
+}
+
+// Text computes the word diff of the two texts. Words are compared
+// case-insensitively and ignoring surrounding punctuation, but the original
+// words are retained in the edits.
+func Text(a, b string) []Edit {
+	return Words(strings.Fields(a), strings.Fields(b))
+}
+
+// Words computes the diff of the two word lists using the Myers algorithm.
+func Words(a, b []string) []Edit {
+	na, nb := normalize(a), normalize(b)
+	n, m := len(na), len(nb)
+	max := n + m
+	off := max + 1
+
+	// trace[d] holds v[-d..d] at the start of round d for backtracking.
+
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	done := false
+	for d := 0; d <= max && !done; d++ {
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && na[x] == nb[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+	}
+
+	// Backtrack from the end to recover the edits in reverse.
+
+	var rev []Edit
+	add := func(op Op, word string) {
+		if len(rev) > 0 && rev[len(rev)-1].Op == op {
+			rev[len(rev)-1].Words = append(rev[len(rev)-1].Words, word)
+			return
+		}
+		rev = append(rev, Edit{Op: op, Words: []string{word}})
+	}
+
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		at := func(k int) int {
+			return vd[k+d]
+		}
+
+		k := x - y
+		var pk int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := 0
+		if d > 0 {
+			px = at(pk)
+		}
+		py := px - pk
+
+		for x > px && y > py {
+			x--
+			y--
+			add(Equal, a[x])
+		}
+		if d > 0 {
+			if x == px {
+				y--
+				add(Insert, b[y])
+			} else {
+				x--
+				add(Delete, a[x])
+			}
+		}
+	}
+
+	// Reverse edits and words within edits.
+
+	ret := make([]Edit, len(rev))
+	for i, e := range rev {
+		for l, r := 0, len(e.Words)-1; l < r; l, r = l+1, r-1 {
+			e.Words[l], e.Words[r] = e.Words[r], e.Words[l]
+		}
+		ret[len(rev)-1-i] = e
+	}
+	return ret
+}
+
+// Summarize returns the statistics of the given edits.
+func Summarize(edits []Edit) Stats {
+	var s Stats
+	for i, e := range edits {
+		switch e.Op {
+		case Equal:
+			s.Words += len(e.Words)
+		case Delete:
+			s.Words += len(e.Words)
+			if i+1 < len(edits) && edits[i+1].Op == Insert {
+				sub := min(len(e.Words), len(edits[i+1].Words))
+				s.Substitutions += sub
+				s.Deletions += len(e.Words) - sub
+			} else {
+				s.Deletions += len(e.Words)
+			}
+		case Insert:
+			if i > 0 && edits[i-1].Op == Delete {
+				s.Insertions += len(e.Words) - min(len(e.Words), len(edits[i-1].Words))
+			} else {
+				s.Insertions += len(e.Words)
+			}
+		}
+	}
+	return s
+}
+
+func normalize(words []string) []string {
+	ret := make([]string, len(words))
+	for i, w := range words {
+		ret[i] = strings.ToLower(strings.Trim(w, ".,;:!?\"'()"))
+	}
+	return ret
+}
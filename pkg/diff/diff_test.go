@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected []Edit
+	}{
+		{"", "", []Edit{}},
+		{"a b c", "a b c", []Edit{{Equal, []string{"a", "b", "c"}}}},
+		{"Hello, world.", "hello world", []Edit{{Equal, []string{"Hello,", "world."}}}},
+		{"a b c", "a x c", []Edit{{Equal, []string{"a"}}, {Delete, []string{"b"}}, {Insert, []string{"x"}}, {Equal, []string{"c"}}}},
+		{"a b c", "a c", []Edit{{Equal, []string{"a"}}, {Delete, []string{"b"}}, {Equal, []string{"c"}}}},
+		{"a c", "a b c", []Edit{{Equal, []string{"a"}}, {Insert, []string{"b"}}, {Equal, []string{"c"}}}},
+		{"", "a b", []Edit{{Insert, []string{"a", "b"}}}},
+		{"a b", "", []Edit{{Delete, []string{"a", "b"}}}},
+	}
+
+	for _, tt := range tests {
+		if actual := Text(tt.a, tt.b); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Text(%q, %q) = %v, want %v", tt.a, tt.b, actual, tt.expected)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected Stats
+		rate     float64
+	}{
+		{"a b c d", "a b c d", Stats{Words: 4}, 0},
+		{"a b c d", "a x c d", Stats{Words: 4, Substitutions: 1}, 0.25},
+		{"a b c d", "a c d", Stats{Words: 4, Deletions: 1}, 0.25},
+		{"a b c d", "a b y c d", Stats{Words: 4, Insertions: 1}, 0.25},
+		{"a b c d", "a x y z d", Stats{Words: 4, Substitutions: 2, Insertions: 1}, 0.75},
+		{"", "a", Stats{Insertions: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		actual := Summarize(Text(tt.a, tt.b))
+		if actual != tt.expected || actual.Rate() != tt.rate {
+			t.Errorf("Summarize(%q, %q) = %+v (rate %v), want %+v (rate %v)", tt.a, tt.b, actual, actual.Rate(), tt.expected, tt.rate)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "google.txt"), filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(a, []byte("the <quick> fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("the slow fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Compare(a, b, "")
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if r.Title != "google.txt" || r.A != "google.txt" || r.B != "other.txt" || len(r.Edits) != 4 {
+		t.Errorf("Compare = %+v, want 4 edits of google.txt and other.txt", r)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	if html := buf.String(); !strings.Contains(html, "&lt;quick&gt;") || !strings.Contains(html, "slow") {
+		t.Errorf("WriteHTML = %v, want escaped words of both transcripts", html)
+	}
+
+	if _, err := Compare(filepath.Join(dir, "missing.txt"), b, ""); err == nil {
+		t.Errorf("Compare(missing) succeeded, want error")
+	}
+}
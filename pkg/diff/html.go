@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Report is a comparison of two transcripts of the same audio.
+type Report struct {
+	// Title is the title of the report, such as the audio file name.
+	Title string
+	// A and B are the names of the compared transcripts, such as the provider.
+	A, B string
+	// Edits are the differences.
+	Edits []Edit
+}
+
+// Compare compares the two transcript files. If the title is empty, the name of
+// the first transcript is used.
+func Compare(a, b, title string) (Report, error) {
+	ta, err := ioutil.ReadFile(a)
+	if err != nil {
+		return Report{}, err
+	}
+	tb, err := ioutil.ReadFile(b)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{
+		Title: title,
+		A:     filepath.Base(a),
+		B:     filepath.Base(b),
+		Edits: Text(string(ta), string(tb)),
+	}
+	if r.Title == "" {
+		r.Title = r.A
+	}
+	return r, nil
+}
+
+// WriteHTML renders the report as a self-contained HTML page. Words only in A
+// are shown struck-through in red, words only in B underlined in green and
+// substitutions highlighted in yellow.
+func WriteHTML(w io.Writer, r Report) error {
+	type span struct {
+		Class string
+		Text  string
+	}
+
+	var spans []span
+	for i, e := range r.Edits {
+		text := strings.Join(e.Words, " ")
+		switch e.Op {
+		case Equal:
+			spans = append(spans, span{Class: "eq", Text: text})
+		case Delete:
+			if i+1 < len(r.Edits) && r.Edits[i+1].Op == Insert {
+				spans = append(spans, span{Class: "sub del", Text: text})
+			} else {
+				spans = append(spans, span{Class: "del", Text: text})
+			}
+		case Insert:
+			if i > 0 && r.Edits[i-1].Op == Delete {
+				spans = append(spans, span{Class: "sub ins", Text: text})
+			} else {
+				spans = append(spans, span{Class: "ins", Text: text})
+			}
+		}
+	}
+
+	s := Summarize(r.Edits)
+	return page.Execute(w, map[string]interface{}{
+		"Title": r.Title,
+		"A":     r.A,
+		"B":     r.B,
+		"Stats": s,
+		"Rate":  s.Rate() * 100,
+		"Spans": spans,
+	})
+}
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript comparison: {{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; line-height: 1.6; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td { padding: 0.2em 1em; border-bottom: 1px solid #ddd; }
+.del { color: #a00; background: #fdd; text-decoration: line-through; }
+.ins { color: #060; background: #dfd; text-decoration: underline; }
+.sub { background: #ffc; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><td>Compared</td><td><span class="del">{{.A}}</span> vs <span class="ins">{{.B}}</span></td></tr>
+<tr><td>Words</td><td>{{.Stats.Words}}</td></tr>
+<tr><td>Substitutions</td><td>{{.Stats.Substitutions}}</td></tr>
+<tr><td>Deletions</td><td>{{.Stats.Deletions}}</td></tr>
+<tr><td>Insertions</td><td>{{.Stats.Insertions}}</td></tr>
+<tr><td>Difference rate</td><td>{{printf "%.1f" .Rate}}%</td></tr>
+</table>
+<p>{{range .Spans}}<span class="{{.Class}}">{{.Text}}</span> {{end}}</p>
+</body>
+</html>
+`))
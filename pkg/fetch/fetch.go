@@ -0,0 +1,93 @@
+// Package fetch contains fetchers of input audio by URI scheme, such as local
+// files, gs:// and s3:// objects, http(s) URLs and rss:// podcast feeds.
+// Fetchers are registered by scheme, so new input schemes can be supported by
+// registering a fetcher.
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fetcher makes input audio available as local files.
+type Fetcher interface {
+	// Scheme returns the URI scheme handled by the fetcher, such as "gs".
+	Scheme() string
+	// Fetch fetches the audio at the given URI into the given directory, if
+	// needed. It returns the local filename, which has the same base name as
+	// the URI, plus an extension if the URI has none.
+	Fetch(ctx context.Context, uri, dir string) (string, error)
+}
+
+// InPlace is implemented by fetchers of URIs that may be transcribed in place,
+// without fetching, such as GCS objects.
+type InPlace interface {
+	// InPlace returns true iff the audio at the given URI can be read by the
+	// provider directly.
+	InPlace(uri string) bool
+}
+
+// fetchers are the registered fetchers by scheme.
+var fetchers = map[string]Fetcher{}
+
+func init() {
+	Register(local{})
+}
+
+// Register registers a fetcher, replacing any fetcher for the same scheme.
+func Register(f Fetcher) {
+	fetchers[f.Scheme()] = f
+}
+
+// Lookup returns the registered fetcher for the given scheme.
+func Lookup(scheme string) (Fetcher, error) {
+	f, ok := fetchers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported scheme: %v. Supported: %v", scheme, strings.Join(Schemes(), ", "))
+	}
+	return f, nil
+}
+
+// Schemes returns the schemes of the registered fetchers.
+func Schemes() []string {
+	var ret []string
+	for s := range fetchers {
+		ret = append(ret, s)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Scheme returns the scheme of the given URI. Plain paths, including Windows
+// paths such as "D:\foo.wav", are local files with the "file" scheme.
+func Scheme(uri string) string {
+	i := strings.Index(uri, "://")
+	if i < 2 {
+		return LocalScheme
+	}
+	return strings.ToLower(uri[:i])
+}
+
+// Fetch fetches the audio at the given URI into the given directory, if
+// needed, using the fetcher registered for its scheme. It returns the local
+// filename.
+func Fetch(ctx context.Context, uri, dir string) (string, error) {
+	f, err := Lookup(Scheme(uri))
+	if err != nil {
+		return "", err
+	}
+	return f.Fetch(ctx, uri, dir)
+}
+
+// IsInPlace returns true iff the given URI may be transcribed in place per the
+// fetcher registered for its scheme.
+func IsInPlace(uri string) bool {
+	f, err := Lookup(Scheme(uri))
+	if err != nil {
+		return false
+	}
+	p, ok := f.(InPlace)
+	return ok && p.InPlace(uri)
+}
@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/herohde/transcribe/pkg/util/pathx"
+	"github.com/herohde/transcribe/pkg/util/storagex"
+	"google.golang.org/api/storage/v1"
+)
+
+// GCSScheme is the scheme of GCS objects.
+const GCSScheme = "gs"
+
+func init() {
+	Register(&gcs{})
+}
+
+// gcs fetches GCS objects by downloading them. Flac and wav objects, which the
+// Speech API reads directly, are transcribed in place instead. The client is
+// created on first use with Application Default Credentials.
+type gcs struct {
+	mu sync.Mutex
+	cl *storage.Service
+}
+
+func (g *gcs) Scheme() string {
+	return GCSScheme
+}
+
+func (g *gcs) InPlace(uri string) bool {
+	switch strings.ToLower(path.Ext(uri)) {
+	case ".flac", ".wav":
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *gcs) Fetch(ctx context.Context, uri, dir string) (string, error) {
+	bucket, object, err := storagex.ParseURI(uri)
+	if err != nil {
+		return "", err
+	}
+	cl, err := g.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sub, err := os.MkdirTemp(dir, "gs")
+	if err != nil {
+		return "", err
+	}
+	ret := filepath.Join(sub, pathx.SafeName(path.Base(object)))
+	if err := storagex.DownloadFile(ctx, cl, bucket, object, ret); err != nil {
+		return "", err
+	}
+	return ret, nil
+}
+
+func (g *gcs) client(ctx context.Context) (*storage.Service, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cl == nil {
+		cl, err := storagex.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		g.cl = cl
+	}
+	return g.cl, nil
+}
@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/util/pathx"
+	"github.com/seekerror/logw"
+)
+
+// DownloadAttempts is the maximum number of attempts of an HTTP download. An
+// interrupted download is resumed where it left off, if the server supports
+// range requests.
+const DownloadAttempts = 5
+
+func init() {
+	Register(web{scheme: "http"})
+	Register(web{scheme: "https"})
+}
+
+// web fetches http(s) URLs, such as podcast episodes, by downloading them.
+type web struct {
+	scheme string
+}
+
+func (w web) Scheme() string {
+	return w.scheme
+}
+
+func (w web) Fetch(ctx context.Context, uri, dir string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	// Download into a directory of its own, so that URLs with the same base
+	// name do not collide. A partial download is kept as '.part' until done.
+
+	sub, err := os.MkdirTemp(dir, "http")
+	if err != nil {
+		return "", err
+	}
+	name := pathx.SafeName(path.Base(u.Path))
+	if name == "." || name == "_" || name == "" {
+		name = "download"
+	}
+	part := filepath.Join(sub, name+".part")
+
+	var resp *http.Response
+	var validator string
+	for i := 1; ; i++ {
+		var retry bool
+		resp, retry, err = download(ctx, uri, part, &validator)
+		if err == nil || !retry || i >= DownloadAttempts || ctx.Err() != nil {
+			break
+		}
+		logw.Infof(ctx, "Download of %v interrupted: %v. Resuming", uri, err)
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Duration(i) * time.Second):
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if path.Ext(name) == "" {
+		// Use the content type for the extension, such as 'audio/mpeg' for mp3.
+
+		if t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if exts, err := mime.ExtensionsByType(t); err == nil && len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+	}
+	ret := filepath.Join(sub, name)
+	if err := os.Rename(part, ret); err != nil {
+		return "", err
+	}
+	return ret, nil
+}
+
+// download downloads the URL into the given file, resuming from its current
+// size if it exists. The validator is the ETag or Last-Modified date of the
+// first response. It is sent as If-Range on resume, so that a resource that
+// changed in the meantime is downloaded from the start. A download without a
+// validator is not resumed. It returns the response on success and otherwise
+// whether the download may be resumed.
+func download(ctx context.Context, uri, filename string, validator *string) (*http.Response, bool, error) {
+	var offset int64
+	if fi, err := os.Stat(filename); err == nil && *validator != "" {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+		req.Header.Set("If-Range", *validator)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		return resp, false, nil // already complete
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC // range not supported or resource changed: start over
+		*validator = rangeValidator(resp.Header)
+	default:
+		return nil, resp.StatusCode >= 500, fmt.Errorf("failed to download %v: %v", uri, resp.Status)
+	}
+
+	fd, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := io.Copy(fd, resp.Body); err != nil {
+		fd.Close()
+		return nil, true, err
+	}
+	return resp, false, fd.Close()
+}
+
+// rangeValidator returns the strong ETag or else the Last-Modified date of the
+// response, if any. Weak ETags cannot be used with If-Range.
+func rangeValidator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
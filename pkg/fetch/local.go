@@ -0,0 +1,21 @@
+package fetch
+
+import (
+	"context"
+	"strings"
+)
+
+// LocalScheme is the scheme of local files.
+const LocalScheme = "file"
+
+// local fetches local files, which are used in place. Whether the file exists
+// is left to the caller.
+type local struct{}
+
+func (local) Scheme() string {
+	return LocalScheme
+}
+
+func (local) Fetch(ctx context.Context, uri, dir string) (string, error) {
+	return strings.TrimPrefix(uri, LocalScheme+"://"), nil
+}
@@ -0,0 +1,65 @@
+package fetch
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxFeedSize is the maximum size of an RSS feed.
+const maxFeedSize = 32 << 20
+
+func init() {
+	Register(rss{})
+}
+
+// rss fetches the latest episode of a podcast feed, such as
+// "rss://example.com/feed.xml", by downloading the enclosure of the first item.
+// The feed itself is read over https.
+type rss struct{}
+
+func (rss) Scheme() string {
+	return "rss"
+}
+
+func (rss) Fetch(ctx context.Context, uri, dir string) (string, error) {
+	feed := "https://" + uri[len("rss://"):]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read feed %v: %v", feed, resp.Status)
+	}
+
+	var doc struct {
+		Items []struct {
+			Enclosure struct {
+				URL string `xml:"url,attr"`
+			} `xml:"enclosure"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxFeedSize)).Decode(&doc); err != nil {
+		return "", fmt.Errorf("invalid feed %v: %v", feed, err)
+	}
+	for _, item := range doc.Items {
+		url := strings.TrimSpace(item.Enclosure.URL)
+		if url == "" {
+			continue
+		}
+		if s := Scheme(url); s != "http" && s != "https" {
+			return "", fmt.Errorf("unsupported episode URL in feed %v: %v", feed, url)
+		}
+		return Fetch(ctx, url, dir)
+	}
+	return "", fmt.Errorf("no episodes in feed %v", feed)
+}
@@ -0,0 +1,49 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/herohde/transcribe/pkg/util/pathx"
+)
+
+func init() {
+	Register(s3{})
+}
+
+// s3 fetches S3 objects, such as "s3://bucket/path/foo.mp3", with the AWS CLI,
+// if installed, which uses the configured AWS credentials. Otherwise, objects
+// are downloaded anonymously over https, such as from public archives.
+type s3 struct{}
+
+func (s3) Scheme() string {
+	return "s3"
+}
+
+func (s3) Fetch(ctx context.Context, uri, dir string) (string, error) {
+	rest := strings.TrimPrefix(uri[len("s3://"):], "/")
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return "", fmt.Errorf("invalid S3 URI: %v", uri)
+	}
+	bucket, key := rest[:i], rest[i+1:]
+
+	if _, err := exec.LookPath("aws"); err != nil {
+		return web{scheme: "https"}.Fetch(ctx, fmt.Sprintf("https://%v.s3.amazonaws.com/%v", bucket, key), dir)
+	}
+
+	sub, err := os.MkdirTemp(dir, "s3")
+	if err != nil {
+		return "", err
+	}
+	ret := filepath.Join(sub, pathx.SafeName(path.Base(key)))
+	if out, err := exec.CommandContext(ctx, "aws", "s3", "cp", "--only-show-errors", uri, ret).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("aws s3 cp failed: %v: %v", err, strings.TrimSpace(string(out)))
+	}
+	return ret, nil
+}
@@ -0,0 +1,24 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// csvFormat formats a CSV row per phrase.
+type csvFormat struct{}
+
+func (csvFormat) Name() string {
+	return "csv"
+}
+
+func (csvFormat) Ext() string {
+	return ".csv"
+}
+
+func (csvFormat) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteCSV(&buf, r.Phrases)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,93 @@
+// Package format contains formatters of transcription results, such as text or
+// SRT subtitles. Formatters are registered by name, so new output formats can
+// be supported by registering a formatter.
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// Options are formatting options. Each formatter uses the relevant ones.
+type Options struct {
+	// Subtitles are the cue limits for subtitle formats.
+	Subtitles transcribe.SubtitleOptions
+	// Voices indicates that WebVTT cues are tagged with the speaker.
+	Voices bool
+	// AnchorInterval is the minimum interval between Markdown timestamp
+	// headings. If zero, the default is used.
+	AnchorInterval time.Duration
+}
+
+// Result is a transcription result of a file to be formatted.
+type Result struct {
+	// Run is the ID of the run, if any.
+	Run string
+	// File is the name of the audio file, such as "foo.wav".
+	File string
+	// Phrases are the post-processed phrases, such as with low-confidence
+	// words marked or regrouped into turns.
+	Phrases []transcribe.Phrase
+	// Structured are the phrases as recognized, except for redaction, for
+	// structured formats.
+	Structured []transcribe.Phrase
+	// Text is the plain text transcript, such as with sections.
+	Text string
+	// Audio is a link to the audio file, if any, such as for embedding.
+	Audio string
+	// Options are the formatting options.
+	Options Options
+}
+
+// Formatter formats transcription results.
+type Formatter interface {
+	// Name returns the name of the format, such as "srt".
+	Name() string
+	// Ext returns the file extension of the format, such as ".srt".
+	Ext() string
+	// Format formats the result.
+	Format(r Result) ([]byte, error)
+}
+
+// formatters are the registered formatters by name.
+var formatters = map[string]Formatter{}
+
+func init() {
+	Register(text{})
+	Register(srt{})
+	Register(vtt{})
+	Register(lrc{})
+	Register(jsonFormat{})
+	Register(jsonl{})
+	Register(csvFormat{})
+	Register(markdown{})
+	Register(html{})
+}
+
+// Register registers a formatter, replacing any formatter of the same name.
+func Register(f Formatter) {
+	formatters[f.Name()] = f
+}
+
+// Lookup returns the registered formatter with the given name.
+func Lookup(name string) (Formatter, error) {
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format: %v. Supported: %v", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names returns the names of the registered formatters.
+func Names() []string {
+	var ret []string
+	for name := range formatters {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,25 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// html formats an HTML review page, with an audio player if an audio link is
+// given.
+type html struct{}
+
+func (html) Name() string {
+	return "html"
+}
+
+func (html) Ext() string {
+	return ".html"
+}
+
+func (html) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteHTML(&buf, r.File, r.Phrases, r.Audio)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,41 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// jsonFormat formats the structured phrases as a single JSON document.
+type jsonFormat struct{}
+
+func (jsonFormat) Name() string {
+	return "json"
+}
+
+func (jsonFormat) Ext() string {
+	return ".json"
+}
+
+func (jsonFormat) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteJSON(&buf, r.Run, r.File, r.Structured)
+	return buf.Bytes(), err
+}
+
+// jsonl formats the structured phrases as JSON Lines, one per phrase.
+type jsonl struct{}
+
+func (jsonl) Name() string {
+	return "jsonl"
+}
+
+func (jsonl) Ext() string {
+	return ".jsonl"
+}
+
+func (jsonl) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteJSONL(&buf, r.Run, r.File, r.Structured)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,24 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// markdown formats Markdown with timestamp headings.
+type markdown struct{}
+
+func (markdown) Name() string {
+	return "md"
+}
+
+func (markdown) Ext() string {
+	return ".md"
+}
+
+func (markdown) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteMarkdown(&buf, r.File, r.Phrases, r.Options.AnchorInterval)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,58 @@
+package format
+
+import (
+	"bytes"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// srt formats SubRip subtitles.
+type srt struct{}
+
+func (srt) Name() string {
+	return "srt"
+}
+
+func (srt) Ext() string {
+	return ".srt"
+}
+
+func (srt) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteSRT(&buf, transcribe.Cues(r.Phrases, r.Options.Subtitles))
+	return buf.Bytes(), err
+}
+
+// vtt formats WebVTT subtitles, optionally with speaker voice tags.
+type vtt struct{}
+
+func (vtt) Name() string {
+	return "vtt"
+}
+
+func (vtt) Ext() string {
+	return ".vtt"
+}
+
+func (vtt) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteVTT(&buf, transcribe.Cues(r.Phrases, r.Options.Subtitles), r.Options.Voices)
+	return buf.Bytes(), err
+}
+
+// lrc formats line-timed LRC lyrics.
+type lrc struct{}
+
+func (lrc) Name() string {
+	return "lrc"
+}
+
+func (lrc) Ext() string {
+	return ".lrc"
+}
+
+func (lrc) Format(r Result) ([]byte, error) {
+	var buf bytes.Buffer
+	err := transcribe.WriteLRC(&buf, r.File, transcribe.Cues(r.Phrases, r.Options.Subtitles))
+	return buf.Bytes(), err
+}
@@ -0,0 +1,16 @@
+package format
+
+// text formats the plain text transcript.
+type text struct{}
+
+func (text) Name() string {
+	return "text"
+}
+
+func (text) Ext() string {
+	return ".txt"
+}
+
+func (text) Format(r Result) ([]byte, error) {
+	return []byte(r.Text), nil
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// PubSub publishes the event as JSON to a Pub/Sub topic.
+type PubSub struct {
+	cl    *pubsub.Client
+	topic *pubsub.Topic
+}
+
+// NewPubSub returns a notifier for the given topic, in the form
+// "projects/<project>/topics/<topic>".
+func NewPubSub(ctx context.Context, topic string) (*PubSub, error) {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[2] != "topics" {
+		return nil, fmt.Errorf("invalid topic: %v", topic)
+	}
+
+	cl, err := pubsub.NewClient(ctx, parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub client: %v", err)
+	}
+	return &PubSub{cl: cl, topic: cl.Topic(parts[3])}, nil
+}
+
+func (p *PubSub) Notify(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := p.topic.Publish(ctx, &pubsub.Message{Data: data}).Get(ctx); err != nil {
+		return fmt.Errorf("failed to publish to %v: %v", p.topic, err)
+	}
+	return nil
+}
+
+// Close flushes and stops the topic and closes the client.
+func (p *PubSub) Close() error {
+	p.topic.Stop()
+	return p.cl.Close()
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Webhook POSTs the event as JSON to a URL.
+type Webhook struct {
+	url string
+}
+
+// NewWebhook returns a notifier for the given URL.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url}
+}
+
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	return post(ctx, w.url, e)
+}
+
+// Slack posts a message to a Slack incoming webhook.
+type Slack struct {
+	url string
+}
+
+// NewSlack returns a notifier for the given Slack incoming webhook URL.
+func NewSlack(url string) *Slack {
+	return &Slack{url: url}
+}
+
+func (s *Slack) Notify(ctx context.Context, e Event) error {
+	return post(ctx, s.url, map[string]string{"text": e.String()})
+}
+
+func post(ctx context.Context, url string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to notify %v: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to notify %v: %v", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+// maxInsertRows is the maximum number of rows per streaming insert request.
+const maxInsertRows = 500
+
+// Phrase is a row of phrase-level data.
+type Phrase struct {
+	Run        string    `bigquery:"run"`
+	File       string    `bigquery:"file"`
+	Index      int       `bigquery:"index"`
+	Text       string    `bigquery:"text"`
+	Start      float64   `bigquery:"start"` // seconds
+	End        float64   `bigquery:"end"`   // seconds
+	Confidence float64   `bigquery:"confidence"`
+	Time       time.Time `bigquery:"time"`
+}
+
+// BigQuery streams phrase-level data of each transcript into a BigQuery table.
+// The table is created, if it does not exist.
+type BigQuery struct {
+	cl    *bigquery.Client
+	table *bigquery.Table
+}
+
+// NewBigQuery returns a sink for the given table, in the form
+// "[project.]dataset.table". If no project is given, it is detected from the
+// credentials.
+func NewBigQuery(ctx context.Context, table string) (*BigQuery, error) {
+	parts := strings.Split(table, ".")
+	project := bigquery.DetectProjectID
+	switch len(parts) {
+	case 2:
+		// ok
+	case 3:
+		project, parts = parts[0], parts[1:]
+	default:
+		return nil, fmt.Errorf("invalid table: %v", table)
+	}
+
+	cl, err := bigquery.NewClient(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bigquery client: %v", err)
+	}
+	t := cl.Dataset(parts[0]).Table(parts[1])
+
+	if _, err := t.Metadata(ctx); err != nil {
+		var e *googleapi.Error
+		if !errors.As(err, &e) || e.Code != http.StatusNotFound {
+			cl.Close()
+			return nil, fmt.Errorf("failed to lookup table %v: %v", table, err)
+		}
+
+		schema, err := bigquery.InferSchema(Phrase{})
+		if err != nil {
+			cl.Close()
+			return nil, err
+		}
+		if err := t.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
+			cl.Close()
+			return nil, fmt.Errorf("failed to create table %v: %v", table, err)
+		}
+	}
+	return &BigQuery{cl: cl, table: t}, nil
+}
+
+func (b *BigQuery) Write(ctx context.Context, r Record) error {
+	now := time.Now()
+
+	var rows []*bigquery.StructSaver
+	for i, p := range r.Phrases {
+		row := Phrase{
+			Run:        r.Run,
+			File:       r.File,
+			Index:      i,
+			Text:       strings.TrimSpace(p.Text),
+			Confidence: float64(p.Confidence),
+			Time:       now,
+		}
+		if len(p.Words) > 0 {
+			row.Start = p.Words[0].Start.Seconds()
+			row.End = p.Words[len(p.Words)-1].End.Seconds()
+		}
+
+		// Use a deterministic insert ID, so that retried inserts are deduplicated.
+		id := fmt.Sprintf("%v/%v/%v", r.Run, r.File, i)
+		rows = append(rows, &bigquery.StructSaver{Struct: row, InsertID: id})
+	}
+
+	ins := b.table.Inserter()
+	for len(rows) > 0 {
+		n := len(rows)
+		if n > maxInsertRows {
+			n = maxInsertRows
+		}
+		if err := ins.Put(ctx, rows[:n]); err != nil {
+			return fmt.Errorf("failed to insert %v into %v: %v", r.File, b.table.FullyQualifiedName(), err)
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+func (b *BigQuery) Close() error {
+	return b.cl.Close()
+}
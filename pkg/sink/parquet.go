@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Word is a row of word-level data.
+type Word struct {
+	File       string  `parquet:"file"`
+	Word       string  `parquet:"word"`
+	Start      float64 `parquet:"start"` // seconds
+	End        float64 `parquet:"end"`   // seconds
+	Confidence float32 `parquet:"confidence"`
+	Speaker    int32   `parquet:"speaker"`
+}
+
+// Parquet writes word-level data of all transcripts to a single Parquet file,
+// which can be loaded into BigQuery, DuckDB, etc., directly.
+type Parquet struct {
+	filename string
+
+	fd *os.File
+	w  *parquet.GenericWriter[Word]
+	mu sync.Mutex
+}
+
+// NewParquet returns a sink that writes to the given file. Any existing file is
+// overwritten.
+func NewParquet(filename string) (*Parquet, error) {
+	fd, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v: %v", filename, err)
+	}
+	return &Parquet{filename: filename, fd: fd, w: parquet.NewGenericWriter[Word](fd)}, nil
+}
+
+func (p *Parquet) Write(ctx context.Context, r Record) error {
+	var rows []Word
+	for _, phrase := range r.Phrases {
+		for _, w := range phrase.Words {
+			rows = append(rows, Word{
+				File:       r.File,
+				Word:       w.Text,
+				Start:      w.Start.Seconds(),
+				End:        w.End.Seconds(),
+				Confidence: w.Confidence,
+				Speaker:    int32(w.Speaker),
+			})
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.w.Write(rows); err != nil {
+		return fmt.Errorf("failed to write %v to %v: %v", r.File, p.filename, err)
+	}
+	return nil
+}
+
+func (p *Parquet) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.w.Close(); err != nil {
+		p.fd.Close()
+		return fmt.Errorf("failed to write %v: %v", p.filename, err)
+	}
+	return p.fd.Close()
+}
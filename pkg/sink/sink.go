@@ -0,0 +1,75 @@
+// Package sink contains sinks for storing transcripts for analysis, such as
+// word-level data in Parquet files or phrase-level data in BigQuery.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/herohde/transcribe/pkg/transcribe"
+)
+
+// Record is the transcript of a single audio file.
+type Record struct {
+	// Run is the run ID.
+	Run string
+	// File is the name of the audio file.
+	File string
+	// Phrases are the transcribed phrases.
+	Phrases []transcribe.Phrase
+}
+
+// Sink stores transcripts. It must be safe for concurrent use.
+type Sink interface {
+	// Write stores the transcript of a file.
+	Write(ctx context.Context, r Record) error
+	// Close flushes any buffered data and releases resources.
+	Close() error
+}
+
+// Parse returns a sink for the given specification, which has one of the
+// following forms:
+//
+//	parquet:<file>         Word-level data written to a local Parquet file.
+//	bigquery:<table>       Phrase-level data streamed into "[project.]dataset.table".
+func Parse(ctx context.Context, spec string) (Sink, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid sink: %v", spec)
+	}
+
+	switch parts[0] {
+	case "parquet":
+		return NewParquet(parts[1])
+	case "bigquery":
+		return NewBigQuery(ctx, parts[1])
+	default:
+		return nil, fmt.Errorf("unknown sink: %v", parts[0])
+	}
+}
+
+// Multi is a sink that writes to all its sinks.
+type Multi []Sink
+
+// Write writes to all sinks and returns the first error, if any.
+func (m Multi) Write(ctx context.Context, r Record) error {
+	var ret error
+	for _, s := range m {
+		if err := s.Write(ctx, r); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// Close closes all sinks and returns the first error, if any.
+func (m Multi) Close() error {
+	var ret error
+	for _, s := range m {
+		if err := s.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
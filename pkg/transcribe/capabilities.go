@@ -0,0 +1,18 @@
+package transcribe
+
+import "time"
+
+// Capabilities describe the limits of a transcription backend, so that callers
+// can validate requests up front.
+type Capabilities struct {
+	// MaxDuration is the maximum audio duration per request. Zero if unlimited.
+	MaxDuration time.Duration
+}
+
+// GoogleCapabilities returns the capabilities of the Google Speech API v1
+// backend used by Submit.
+func GoogleCapabilities() Capabilities {
+	return Capabilities{
+		MaxDuration: 480 * time.Minute,
+	}
+}
@@ -0,0 +1,37 @@
+package transcribe
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCSV writes the phrases as CSV with a header and a "start, end, speaker,
+// confidence, text" row per phrase, with offsets in seconds. The speaker is the
+// speaker of the first word, if diarization is enabled. Offsets are empty for
+// phrases without word-level information.
+func WriteCSV(w io.Writer, phrases []Phrase) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"start", "end", "speaker", "confidence", "text"}); err != nil {
+		return err
+	}
+	for _, p := range phrases {
+		var start, end, speaker, confidence string
+		if n := len(p.Words); n > 0 {
+			start = fmt.Sprintf("%.3f", p.Words[0].Start.Seconds())
+			end = fmt.Sprintf("%.3f", p.Words[n-1].End.Seconds())
+			if s := p.Words[0].Speaker; s != 0 {
+				speaker = fmt.Sprint(s)
+			}
+		}
+		if p.Confidence > 0 {
+			confidence = fmt.Sprintf("%.3f", p.Confidence)
+		}
+		if err := cw.Write([]string{start, end, speaker, confidence, strings.TrimSpace(p.Text)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrQuotaExceeded indicates that a project quota or rate limit was exceeded.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrBadAudioFormat indicates that the audio or its format was rejected.
+	ErrBadAudioFormat = errors.New("bad audio format")
+	// ErrBadChannels indicates that the audio was rejected for its number of
+	// channels, such as stereo audio given as mono. It is an ErrBadAudioFormat.
+	ErrBadChannels = fmt.Errorf("%w: channels", ErrBadAudioFormat)
+	// ErrInvalidArgument indicates that the request was rejected for other
+	// reasons than the audio, such as an unsupported model or language.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrOperationTimeout indicates that the operation did not complete in time.
+	ErrOperationTimeout = errors.New("operation timeout")
+	// ErrPermissionDenied indicates missing or insufficient credentials.
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Error is a transcription error. If the cause is recognized, it matches the
+// corresponding sentinel error, such as ErrQuotaExceeded, via errors.Is.
+type Error struct {
+	// Op is the failed operation, such as "request".
+	Op string
+	// Kind is the sentinel error for the cause, if recognized. Otherwise nil.
+	Kind error
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Kind != nil {
+		return fmt.Sprintf("%v failed (%v): %v", e.Op, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%v failed: %v", e.Op, e.Err)
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && errors.Is(e.Kind, target)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError returns an Error for the given operation with the cause classified.
+func newError(op string, err error) error {
+	return &Error{Op: op, Kind: classify(err), Err: err}
+}
+
+func classify(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrOperationTimeout
+	}
+
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return ErrQuotaExceeded
+	case codes.InvalidArgument:
+		return classifyInvalid(status.Convert(err).Message())
+	case codes.DeadlineExceeded:
+		return ErrOperationTimeout
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrPermissionDenied
+	default:
+		return nil
+	}
+}
+
+// classifyInvalid classifies an invalid argument by the error message, which
+// names the rejected field or header, such as "Must use single channel (mono)
+// audio, but WAV header indicates 2 channels".
+func classifyInvalid(msg string) error {
+	msg = strings.ToLower(msg)
+	switch {
+	case strings.Contains(msg, "channel"):
+		return ErrBadChannels
+	case strings.Contains(msg, "sample_rate"), strings.Contains(msg, "sample rate"), strings.Contains(msg, "encoding"), strings.Contains(msg, "header"), strings.Contains(msg, "audio"):
+		return ErrBadAudioFormat
+	default:
+		return ErrInvalidArgument
+	}
+}
@@ -0,0 +1,48 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error // nil if unclassified
+	}{
+		{status.Error(codes.ResourceExhausted, "quota exceeded"), ErrQuotaExceeded},
+		{status.Error(codes.InvalidArgument, "Must use single channel (mono) audio, but WAV header indicates 2 channels."), ErrBadChannels},
+		{status.Error(codes.InvalidArgument, "audio_channel_count (1) in RecognitionConfig must either be unspecified or match the value in the WAV header (2)."), ErrBadChannels},
+		{status.Error(codes.InvalidArgument, "sample_rate_hertz (16000) in RecognitionConfig must either be unspecified or match the value in the FLAC header (44100)."), ErrBadAudioFormat},
+		{status.Error(codes.InvalidArgument, "Invalid recognition 'config': bad encoding.."), ErrBadAudioFormat},
+		{status.Error(codes.InvalidArgument, "Invalid recognition 'config': The requested model is currently not supported for language : de-DE."), ErrInvalidArgument},
+		{status.Error(codes.DeadlineExceeded, "deadline"), ErrOperationTimeout},
+		{fmt.Errorf("wait: %w", context.DeadlineExceeded), ErrOperationTimeout},
+		{status.Error(codes.PermissionDenied, "denied"), ErrPermissionDenied},
+		{status.Error(codes.Unauthenticated, "no credentials"), ErrPermissionDenied},
+		{status.Error(codes.Internal, "internal"), nil},
+		{errors.New("other"), nil},
+	}
+
+	sentinels := []error{ErrQuotaExceeded, ErrBadAudioFormat, ErrBadChannels, ErrInvalidArgument, ErrOperationTimeout, ErrPermissionDenied}
+
+	for _, tt := range tests {
+		err := newError("request", tt.err)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("newError(%v) does not wrap the error", tt.err)
+		}
+
+		for _, s := range sentinels {
+			// Channel errors are also bad audio formats.
+			expected := s == tt.expected || (s == ErrBadAudioFormat && tt.expected == ErrBadChannels)
+			if actual := errors.Is(err, s); actual != expected {
+				t.Errorf("errors.Is(newError(%v), %v) = %v, want %v", tt.err, s, actual, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// WriteHTML writes the phrases as a self-contained HTML review page with the
+// given title and a paragraph per phrase, split at speaker changes. Words are
+// time-linked spans with data-start and data-end attributes in seconds. If an
+// audio source is given, such as a relative path to the audio file, the page
+// embeds an audio player and clicking a word or timestamp plays from there.
+func WriteHTML(w io.Writer, title string, phrases []Phrase, audio string) error {
+	type word struct {
+		Text       string
+		Start, End string
+	}
+	type paragraph struct {
+		Label     string
+		Start     string
+		Timestamp string
+		Text      string // if no words
+		Words     []word
+	}
+
+	var paras []paragraph
+	for _, p := range phrases {
+		for _, sp := range splitSpeakers(p) {
+			para := paragraph{}
+			switch {
+			case len(sp.Words) > 0 && sp.Words[0].Speaker != 0:
+				para.Label = fmt.Sprintf("Speaker %v", sp.Words[0].Speaker)
+			case sp.Channel != 0:
+				para.Label = fmt.Sprintf("Channel %v", sp.Channel)
+			}
+			if len(sp.Words) == 0 {
+				para.Text = sp.Text
+				paras = append(paras, para)
+				continue
+			}
+
+			para.Start = fmt.Sprintf("%.3f", seconds(start(sp)))
+			para.Timestamp = FormatTimestamp(start(sp))
+			for _, w := range sp.Words {
+				para.Words = append(para.Words, word{
+					Text:  w.Text,
+					Start: fmt.Sprintf("%.3f", seconds(w.Start)),
+					End:   fmt.Sprintf("%.3f", seconds(w.End)),
+				})
+			}
+			paras = append(paras, para)
+		}
+	}
+
+	return transcriptPage.Execute(w, map[string]interface{}{
+		"Title":      title,
+		"Audio":      template.URL(audio), // trusted: not user content
+		"Paragraphs": paras,
+	})
+}
+
+var transcriptPage = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript: {{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; line-height: 1.6; }
+audio { position: sticky; top: 0; width: 100%; }
+.ts { color: #666; font-size: 0.9em; text-decoration: none; margin-right: 0.5em; }
+.label { font-weight: bold; margin-right: 0.5em; }
+[data-start] { cursor: pointer; }
+span[data-start]:hover { background: #ffc; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Audio}}<audio controls preload="metadata" src="{{.Audio}}"></audio>
+{{end}}{{range .Paragraphs}}<p>{{if .Words}}<a class="ts" href="#" data-start="{{.Start}}">[{{.Timestamp}}]</a>{{end}}{{if .Label}}<span class="label">{{.Label}}:</span>{{end}}{{if .Words}}{{range .Words}}<span data-start="{{.Start}}" data-end="{{.End}}">{{.Text}}</span> {{end}}{{else}}{{.Text}}{{end}}</p>
+{{end}}<script>
+document.addEventListener("click", function(e) {
+  var audio = document.querySelector("audio");
+  var start = e.target.getAttribute("data-start");
+  if (!audio || start === null) return;
+  e.preventDefault();
+  audio.currentTime = parseFloat(start);
+  audio.play();
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+package transcribe
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Transcript is the JSON representation of the transcript of a file. Offsets
+// are in seconds.
+type Transcript struct {
+	Run     string       `json:"run,omitempty"`
+	File    string       `json:"file"`
+	Phrases []PhraseJSON `json:"phrases"`
+}
+
+// PhraseJSON is the JSON representation of a phrase.
+type PhraseJSON struct {
+	Text       string     `json:"text"`
+	Start      float64    `json:"start"`
+	End        float64    `json:"end"`
+	Confidence float32    `json:"confidence,omitempty"`
+	Channel    int        `json:"channel,omitempty"`
+	Words      []WordJSON `json:"words,omitempty"`
+}
+
+// WordJSON is the JSON representation of a word.
+type WordJSON struct {
+	Text       string  `json:"text"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float32 `json:"confidence,omitempty"`
+	Speaker    int     `json:"speaker,omitempty"`
+}
+
+// WriteJSON writes the phrases of the given file as an indented Transcript in
+// JSON format, so that downstream tools do not have to parse text. The run ID
+// is omitted if empty.
+func WriteJSON(w io.Writer, run, file string, phrases []Phrase) error {
+	t := Transcript{Run: run, File: file, Phrases: []PhraseJSON{}}
+	for _, p := range phrases {
+		t.Phrases = append(t.Phrases, toPhraseJSON(p))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteJSONL writes the phrases of the given file in JSON Lines format, i.e.,
+// a PhraseJSON object with additional "run" and "file" fields per line, for
+// streaming ingestion into data pipelines.
+func WriteJSONL(w io.Writer, run, file string, phrases []Phrase) error {
+	type line struct {
+		Run  string `json:"run,omitempty"`
+		File string `json:"file"`
+		PhraseJSON
+	}
+
+	enc := json.NewEncoder(w)
+	for _, p := range phrases {
+		if err := enc.Encode(line{Run: run, File: file, PhraseJSON: toPhraseJSON(p)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteWords writes the words of the phrases of the given file as an indented
+// JSON object with a flat list of words, such as for forced alignment or
+// karaoke-style display.
+func WriteWords(w io.Writer, run, file string, phrases []Phrase) error {
+	t := struct {
+		Run   string     `json:"run,omitempty"`
+		File  string     `json:"file"`
+		Words []WordJSON `json:"words"`
+	}{Run: run, File: file, Words: []WordJSON{}}
+	for _, p := range phrases {
+		t.Words = append(t.Words, toPhraseJSON(p).Words...)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+func toPhraseJSON(p Phrase) PhraseJSON {
+	ret := PhraseJSON{
+		Text:       p.Text,
+		Confidence: p.Confidence,
+		Channel:    p.Channel,
+	}
+	if n := len(p.Words); n > 0 {
+		ret.Start, ret.End = seconds(p.Words[0].Start), seconds(p.Words[n-1].End)
+	}
+	for _, w := range p.Words {
+		ret.Words = append(ret.Words, WordJSON{
+			Text:       w.Text,
+			Start:      seconds(w.Start),
+			End:        seconds(w.End),
+			Confidence: w.Confidence,
+			Speaker:    w.Speaker,
+		})
+	}
+	return ret
+}
+
+// seconds returns the offset in seconds with millisecond precision.
+func seconds(d time.Duration) float64 {
+	return float64(d.Milliseconds()) / 1000
+}
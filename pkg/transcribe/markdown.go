@@ -0,0 +1,74 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultAnchorInterval is the default minimum interval between timestamp
+// headings in Markdown.
+const DefaultAnchorInterval = time.Minute
+
+// WriteMarkdown writes the phrases as a Markdown document with the given title
+// and a paragraph per phrase, split at speaker changes. Paragraphs are labelled
+// with the speaker or channel, if known. A "## [hh:mm:ss]" heading, which also
+// serves as link anchor, precedes the first paragraph and then the first
+// paragraph after every interval.
+func WriteMarkdown(w io.Writer, title string, phrases []Phrase, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultAnchorInterval
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %v\n", title)
+
+	next := time.Duration(-1)
+	for _, p := range phrases {
+		for _, para := range splitSpeakers(p) {
+			if at := start(para); len(para.Words) > 0 && at >= next {
+				fmt.Fprintf(&sb, "\n## [%v]\n", FormatTimestamp(at))
+				next = at.Truncate(interval) + interval
+			}
+
+			sb.WriteString("\n")
+			switch {
+			case len(para.Words) > 0 && para.Words[0].Speaker != 0:
+				fmt.Fprintf(&sb, "**Speaker %v:** ", para.Words[0].Speaker)
+			case para.Channel != 0:
+				fmt.Fprintf(&sb, "**Channel %v:** ", para.Channel)
+			}
+			sb.WriteString(strings.TrimSpace(para.Text))
+			sb.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// splitSpeakers splits the phrase at speaker changes. A phrase with a single
+// speaker is returned as-is.
+func splitSpeakers(p Phrase) []Phrase {
+	var ret []Phrase
+	for _, w := range p.Words {
+		if n := len(ret); n > 0 && ret[n-1].Words[len(ret[n-1].Words)-1].Speaker == w.Speaker {
+			ret[n-1].Words = append(ret[n-1].Words, w)
+			continue
+		}
+		ret = append(ret, Phrase{Words: []Word{w}, Channel: p.Channel, Confidence: p.Confidence})
+	}
+	if len(ret) <= 1 {
+		return []Phrase{p}
+	}
+
+	for i, para := range ret {
+		texts := make([]string, len(para.Words))
+		for j, w := range para.Words {
+			texts[j] = w.Text
+		}
+		ret[i].Text = strings.Join(texts, " ")
+	}
+	return ret
+}
@@ -0,0 +1,48 @@
+package transcribe
+
+import (
+	"math"
+	"time"
+
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// List prices of the Google Speech API v1 in USD per minute of audio. Projects
+// that opt in to data logging are charged the discounted price.
+const (
+	StandardPrice        = 0.024
+	StandardLoggingPrice = 0.016
+	EnhancedPrice        = 0.036
+	EnhancedLoggingPrice = 0.024
+)
+
+// BillingIncrement is the increment that the audio of each request is rounded
+// up to for billing.
+const BillingIncrement = 15 * time.Second
+
+// IsEnhanced returns true iff the config uses an enhanced model, which is
+// billed at the enhanced price.
+func IsEnhanced(config *speechpb.RecognitionConfig) bool {
+	return config != nil && (config.UseEnhanced || config.Model == "video")
+}
+
+// Price returns the list price in USD per minute of audio for standard or
+// enhanced models, with or without data logging.
+func Price(enhanced, logging bool) float64 {
+	switch {
+	case enhanced && logging:
+		return EnhancedLoggingPrice
+	case enhanced:
+		return EnhancedPrice
+	case logging:
+		return StandardLoggingPrice
+	default:
+		return StandardPrice
+	}
+}
+
+// Billed returns the billed duration of a request with audio of the given
+// duration, i.e., rounded up to the billing increment.
+func Billed(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(float64(d)/float64(BillingIncrement))) * BillingIncrement
+}
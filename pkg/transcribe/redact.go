@@ -0,0 +1,83 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Redacted is the replacement text for redacted words.
+const Redacted = "[redacted]"
+
+// Redaction is a redacted interval of the audio.
+type Redaction struct {
+	// Start and End are the offsets of the redacted word in the audio.
+	Start, End time.Duration
+	// Text is the original, redacted text.
+	Text string
+}
+
+// Redact replaces words matching any of the given keywords with "[redacted]".
+// Matching is case-insensitive and ignores surrounding punctuation. It returns
+// the redacted phrases and the time intervals of the redacted words. Phrases
+// without word-level information are redacted in the text only.
+func Redact(phrases []Phrase, keywords []string) ([]Phrase, []Redaction) {
+	m := map[string]bool{}
+	for _, k := range keywords {
+		if k = normalizeWord(k); k != "" {
+			m[k] = true
+		}
+	}
+
+	var ret []Phrase
+	var list []Redaction
+	for _, p := range phrases {
+		if len(p.Words) == 0 {
+			fields := strings.Fields(p.Text)
+			for i, f := range fields {
+				if m[normalizeWord(f)] {
+					fields[i] = Redacted
+				}
+			}
+			p.Text = strings.Join(fields, " ")
+			ret = append(ret, p)
+			continue
+		}
+
+		words := make([]Word, len(p.Words))
+		texts := make([]string, len(p.Words))
+		for i, w := range p.Words {
+			if m[normalizeWord(w.Text)] {
+				list = append(list, Redaction{Start: w.Start, End: w.End, Text: w.Text})
+				w.Text = Redacted
+			}
+			words[i] = w
+			texts[i] = w.Text
+		}
+		p.Words = words
+		p.Text = strings.Join(texts, " ")
+		ret = append(ret, p)
+	}
+	return ret, list
+}
+
+// WriteEDL writes the redactions as an edit decision list with a tab-separated
+// "start end text" line per redaction, with times in seconds. The format is
+// compatible with Audacity labels and easy to turn into ffmpeg or sox filters
+// to bleep the original audio.
+func WriteEDL(w io.Writer, list []Redaction) error {
+	for _, r := range list {
+		if _, err := fmt.Fprintf(w, "%.3f\t%.3f\t%v\n", r.Start.Seconds(), r.End.Seconds(), r.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	}))
+}
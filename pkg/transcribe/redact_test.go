@@ -0,0 +1,70 @@
+package transcribe
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		phrases  []Phrase
+		keywords []string
+		expected []string
+		list     []Redaction
+	}{
+		{
+			"words",
+			[]Phrase{{Words: []Word{word("call", 0, 1, 0), word("Alice,", 1, 2, 0), word("now", 2, 3, 0)}}},
+			[]string{"alice"},
+			[]string{"call [redacted] now"},
+			[]Redaction{{Start: time.Second, End: 2 * time.Second, Text: "Alice,"}},
+		},
+		{
+			"text",
+			[]Phrase{{Text: "Call  Bob! or bobby"}},
+			[]string{" BOB ", ""},
+			[]string{"Call [redacted] or bobby"},
+			nil,
+		},
+		{
+			"none",
+			[]Phrase{{Text: "hello"}},
+			nil,
+			[]string{"hello"},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		phrases, list := Redact(tt.phrases, tt.keywords)
+
+		var actual []string
+		for _, p := range phrases {
+			actual = append(actual, p.Text)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) || !reflect.DeepEqual(list, tt.list) {
+			t.Errorf("Redact(%v) = %q, %v, want %q, %v", tt.name, actual, list, tt.expected, tt.list)
+		}
+	}
+
+	// The input is not modified.
+	in := []Phrase{{Words: []Word{word("secret", 0, 1, 0)}}}
+	Redact(in, []string{"secret"})
+	if in[0].Words[0].Text != "secret" {
+		t.Errorf("Redact modified its input: %v", in)
+	}
+}
+
+func TestWriteEDL(t *testing.T) {
+	var buf bytes.Buffer
+	list := []Redaction{{Start: 1500 * time.Millisecond, End: 2 * time.Second, Text: "Alice"}}
+	if err := WriteEDL(&buf, list); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1.500\t2.000\tAlice\n"; buf.String() != expected {
+		t.Errorf("WriteEDL = %q, want %q", buf.String(), expected)
+	}
+}
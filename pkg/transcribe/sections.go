@@ -0,0 +1,165 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTitleWords is the maximum number of words in a section title.
+const maxTitleWords = 8
+
+// Section is a named part of a transcript, such as an agenda item of a meeting.
+type Section struct {
+	// Title is the title of the section. Empty for any leading untitled section.
+	Title string
+	// Start is the offset of the section in the audio.
+	Start time.Duration
+	// Phrases are the phrases of the section.
+	Phrases []Phrase
+}
+
+// Split splits the phrases into sections at spoken markers, such as "next
+// agenda item". A phrase containing a marker (case-insensitive) starts a new
+// section, titled by the words following the marker up to the end of the
+// sentence. Any phrases before the first marker form an untitled section.
+func Split(phrases []Phrase, markers []string) []Section {
+	var ret []Section
+	for _, p := range phrases {
+		if title, ok := findMarker(p.Text, markers); ok {
+			ret = append(ret, Section{Title: title, Start: start(p)})
+		}
+		if len(ret) == 0 {
+			ret = append(ret, Section{Start: start(p)})
+		}
+		ret[len(ret)-1].Phrases = append(ret[len(ret)-1].Phrases, p)
+	}
+	return ret
+}
+
+// SplitAt splits the phrases into the given sections, ordered by start, such
+// as at chapter markers. Each word is added to the last section starting at or
+// before it, so that phrases spanning a section start are split. Words before
+// the first section are added to the first. Phrases without word-level
+// information are added to the current section.
+func SplitAt(phrases []Phrase, sections []Section) []Section {
+	ret := make([]Section, len(sections))
+	copy(ret, sections)
+	if len(ret) == 0 {
+		return nil
+	}
+
+	i := 0
+	for _, p := range phrases {
+		first := i
+		var parts []Phrase // per section from first to i
+		for _, w := range p.Words {
+			for i+1 < len(ret) && ret[i+1].Start <= w.Start {
+				i++
+			}
+			for len(parts) <= i-first {
+				parts = append(parts, Phrase{Channel: p.Channel, Confidence: p.Confidence})
+			}
+			parts[i-first].Words = append(parts[i-first].Words, w)
+		}
+		if len(parts) <= 1 {
+			ret[i].Phrases = append(ret[i].Phrases, p)
+			continue
+		}
+
+		for j, part := range parts {
+			if len(part.Words) == 0 {
+				continue
+			}
+			texts := make([]string, len(part.Words))
+			for k, w := range part.Words {
+				texts[k] = w.Text
+			}
+			part.Text = strings.Join(texts, " ")
+			ret[first+j].Phrases = append(ret[first+j].Phrases, part)
+		}
+	}
+	return ret
+}
+
+// FormatSections formats the sections as text with a "[hh:mm:ss] title" heading
+// before each titled section. The phrases of each section are formatted by the
+// given function, such as PostProcess.
+func FormatSections(sections []Section, format func([]Phrase) string) string {
+	var sb strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		if s.Title != "" {
+			fmt.Fprintf(&sb, "[%v] %v\n\n", FormatTimestamp(s.Start), s.Title)
+		}
+		sb.WriteString(strings.TrimSpace(format(s.Phrases)))
+	}
+	return sb.String()
+}
+
+// FormatTimestamp formats the offset as "hh:mm:ss".
+func FormatTimestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// findMarker returns the title following the first marker in the text, if any.
+// If no words follow the marker, the marker itself is used as title.
+func findMarker(text string, markers []string) (string, bool) {
+	for _, m := range markers {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		i, n, ok := indexFold(text, m)
+		if !ok {
+			continue
+		}
+
+		rest := text[i+n:]
+		if j := strings.IndexAny(rest, ".?!"); j >= 0 {
+			rest = rest[:j]
+		}
+		words := strings.Fields(strings.Trim(rest, " ,:;-"))
+		if len(words) > maxTitleWords {
+			words = words[:maxTitleWords]
+		}
+		if len(words) == 0 {
+			return strings.TrimSpace(text[i : i+n]), true
+		}
+		return strings.Join(words, " "), true
+	}
+	return "", false
+}
+
+// indexFold returns the byte offset and length in s of the first occurrence of
+// substr under Unicode case-folding, if any. Unlike with strings.ToLower, the
+// offsets refer to s even if case-folding changes the length of the text.
+func indexFold(s, substr string) (int, int, bool) {
+	for i := range s {
+		j, k := i, 0
+		for k < len(substr) && j < len(s) {
+			a, n := utf8.DecodeRuneInString(s[j:])
+			b, m := utf8.DecodeRuneInString(substr[k:])
+			if !strings.EqualFold(string(a), string(b)) {
+				break
+			}
+			j, k = j+n, k+m
+		}
+		if k == len(substr) {
+			return i, j - i, true
+		}
+	}
+	return 0, 0, false
+}
+
+// start returns the offset of the phrase, if known.
+func start(p Phrase) time.Duration {
+	if len(p.Words) == 0 {
+		return 0
+	}
+	return p.Words[0].Start
+}
@@ -0,0 +1,118 @@
+package transcribe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexFold(t *testing.T) {
+	tests := []struct {
+		s, substr string
+		i, n      int
+		ok        bool
+	}{
+		{"Next agenda item", "agenda", 5, 6, true},
+		{"NEXT AGENDA", "next agenda", 0, 11, true},
+		{"Grüße an STRASSE", "grüße", 0, 7, true},
+		{"Das ÄRGERNIS", "ärgernis", 4, 9, true},
+		{"ſecret", "secret", 0, 7, true}, // long s folds to s
+		{"K", "K", 0, 1, true},           // Kelvin sign folds to k
+		{"abc", "abcd", 0, 0, false},
+		{"abc", "x", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		i, n, ok := indexFold(tt.s, tt.substr)
+		if i != tt.i || n != tt.n || ok != tt.ok {
+			t.Errorf("indexFold(%q, %q) = %v, %v, %v, want %v, %v, %v", tt.s, tt.substr, i, n, ok, tt.i, tt.n, tt.ok)
+		}
+	}
+}
+
+func TestFindMarker(t *testing.T) {
+	markers := []string{"", "next agenda item", "moving on to"}
+
+	tests := []struct {
+		text     string
+		expected string
+		ok       bool
+	}{
+		{"So, next agenda item: the budget. Any comments?", "the budget", true},
+		{"Moving on to hiring", "hiring", true},
+		{"NEXT AGENDA ITEM.", "NEXT AGENDA ITEM", true},
+		{"next agenda item one two three four five six seven eight nine", "one two three four five six seven eight", true},
+		{"nothing here", "", false},
+	}
+
+	for _, tt := range tests {
+		actual, ok := findMarker(tt.text, markers)
+		if actual != tt.expected || ok != tt.ok {
+			t.Errorf("findMarker(%q) = %q, %v, want %q, %v", tt.text, actual, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	phrases := []Phrase{
+		{Text: "welcome", Words: []Word{word("welcome", 0, 1, 0)}},
+		{Text: "next item budget", Words: []Word{word("next", 10, 11, 0)}},
+		{Text: "numbers"},
+		{Text: "next item hiring", Words: []Word{word("next", 20, 21, 0)}},
+	}
+
+	actual := summarize(Split(phrases, []string{"next item"}))
+	expected := []string{":0s:welcome", "budget:10s:next item budget|numbers", "hiring:20s:next item hiring"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Split = %q, want %q", actual, expected)
+	}
+}
+
+func TestSplitAt(t *testing.T) {
+	phrases := []Phrase{
+		{Text: "a b", Words: []Word{word("a", 0, 1, 0), word("b", 1, 2, 0)}},
+		{Text: "c d e", Words: []Word{word("c", 4, 5, 0), word("d", 5, 6, 0), word("e", 10, 11, 0)}},
+		{Text: "untimed"},
+	}
+	sections := []Section{{Title: "one", Start: time.Second}, {Title: "two", Start: 5 * time.Second}, {Title: "three", Start: 8 * time.Second}}
+
+	actual := summarize(SplitAt(phrases, sections))
+	expected := []string{"one:1s:a b|c", "two:5s:d", "three:8s:e|untimed"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SplitAt = %q, want %q", actual, expected)
+	}
+	if SplitAt(phrases, nil) != nil {
+		t.Errorf("SplitAt(nil) != nil")
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "00:00:00"},
+		{1500 * time.Millisecond, "00:00:01"},
+		{3*time.Hour + 25*time.Minute + 7*time.Second, "03:25:07"},
+	}
+
+	for _, tt := range tests {
+		if actual := FormatTimestamp(tt.d); actual != tt.expected {
+			t.Errorf("FormatTimestamp(%v) = %v, want %v", tt.d, actual, tt.expected)
+		}
+	}
+}
+
+// summarize returns "title:start:text|text.." of each section.
+func summarize(sections []Section) []string {
+	var ret []string
+	for _, s := range sections {
+		var texts []string
+		for _, p := range s.Phrases {
+			texts = append(texts, p.Text)
+		}
+		ret = append(ret, s.Title+":"+s.Start.String()+":"+strings.Join(texts, "|"))
+	}
+	return ret
+}
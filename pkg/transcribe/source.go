@@ -0,0 +1,82 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// MaxInlineSize is the maximum size of inline audio accepted by the API.
+const MaxInlineSize = 10 << 20
+
+// Source is audio to transcribe, either stored in GCS or provided inline.
+type Source interface {
+	audio() (*speechpb.RecognitionAudio, error)
+}
+
+// GCS returns a source for an audio object stored in GCS.
+func GCS(bucket, object string) Source {
+	return gcsSource{bucket: bucket, object: object}
+}
+
+// Bytes returns a source for inline audio data. It is intended for small clips,
+// which then do not need to be staged in GCS. The data must be at most
+// MaxInlineSize bytes.
+func Bytes(data []byte) Source {
+	return bytesSource{data: data}
+}
+
+// Reader returns a source for inline audio data read from the given reader.
+// The data must be at most MaxInlineSize bytes.
+func Reader(r io.Reader) Source {
+	return readerSource{r: r}
+}
+
+type gcsSource struct {
+	bucket, object string
+}
+
+func (s gcsSource) audio() (*speechpb.RecognitionAudio, error) {
+	return &speechpb.RecognitionAudio{
+		AudioSource: &speechpb.RecognitionAudio_Uri{Uri: fmt.Sprintf("gs://%v/%v", s.bucket, s.object)},
+	}, nil
+}
+
+func (s gcsSource) String() string {
+	return fmt.Sprintf("gs://%v/%v", s.bucket, s.object)
+}
+
+type bytesSource struct {
+	data []byte
+}
+
+func (s bytesSource) audio() (*speechpb.RecognitionAudio, error) {
+	if len(s.data) > MaxInlineSize {
+		return nil, fmt.Errorf("%w: inline audio too large: %v bytes. Use GCS instead", ErrBadAudioFormat, len(s.data))
+	}
+	return &speechpb.RecognitionAudio{
+		AudioSource: &speechpb.RecognitionAudio_Content{Content: s.data},
+	}, nil
+}
+
+func (s bytesSource) String() string {
+	return fmt.Sprintf("inline[%v bytes]", len(s.data))
+}
+
+type readerSource struct {
+	r io.Reader
+}
+
+func (s readerSource) audio() (*speechpb.RecognitionAudio, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(s.r, MaxInlineSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %v", err)
+	}
+	return bytesSource{data: data}.audio()
+}
+
+func (s readerSource) String() string {
+	return "inline[reader]"
+}
@@ -0,0 +1,177 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Default limits of subtitle cues. Two lines of at most 42 characters are
+// common subtitle guidelines.
+const (
+	DefaultMaxLineChars   = 42
+	DefaultMaxCueLines    = 2
+	DefaultMaxCueDuration = 7 * time.Second
+)
+
+// SubtitleOptions hold optional settings for subtitle cues.
+type SubtitleOptions struct {
+	// MaxLineChars is the maximum number of characters per line. If zero,
+	// DefaultMaxLineChars is used.
+	MaxLineChars int
+	// MaxLines is the maximum number of lines per cue. If zero,
+	// DefaultMaxCueLines is used.
+	MaxLines int
+	// MaxDuration is the maximum duration of a cue. If zero,
+	// DefaultMaxCueDuration is used.
+	MaxDuration time.Duration
+}
+
+// Cue is a timed subtitle of one or more lines.
+type Cue struct {
+	// Start and End are the offsets of the cue in the audio.
+	Start, End time.Duration
+	// Lines are the lines of text.
+	Lines []string
+	// Speaker is the speaker tag, if diarization is enabled. Zero otherwise.
+	Speaker int
+}
+
+// Cues splits the phrases into subtitle cues using the word offsets. A cue
+// never spans phrases or speakers. The lines of each cue are wrapped on word
+// boundaries to be of similar length. Phrases without word-level information
+// cannot be timed and are omitted.
+func Cues(phrases []Phrase, opts SubtitleOptions) []Cue {
+	if opts.MaxLineChars <= 0 {
+		opts.MaxLineChars = DefaultMaxLineChars
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = DefaultMaxCueLines
+	}
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = DefaultMaxCueDuration
+	}
+
+	var ret []Cue
+	for _, p := range phrases {
+		var cur *Cue
+		for _, w := range p.Words {
+			if cur != nil && (w.End-cur.Start > opts.MaxDuration || w.Speaker != cur.Speaker || !addWord(cur, w.Text, opts)) {
+				ret = append(ret, balance(*cur, opts.MaxLineChars))
+				cur = nil
+			}
+			if cur == nil {
+				cur = &Cue{Start: w.Start, Lines: []string{w.Text}, Speaker: w.Speaker}
+			}
+			cur.End = w.End
+		}
+		if cur != nil {
+			ret = append(ret, balance(*cur, opts.MaxLineChars))
+		}
+	}
+	return ret
+}
+
+// addWord adds the word to the cue, if it fits.
+func addWord(c *Cue, word string, opts SubtitleOptions) bool {
+	last := c.Lines[len(c.Lines)-1]
+	if utf8.RuneCountInString(last)+1+utf8.RuneCountInString(word) <= opts.MaxLineChars {
+		c.Lines[len(c.Lines)-1] = last + " " + word
+		return true
+	}
+	if len(c.Lines) < opts.MaxLines {
+		c.Lines = append(c.Lines, word)
+		return true
+	}
+	return false
+}
+
+// balance re-wraps the lines of the cue on word boundaries, so that the lines
+// are of similar length rather than a full line followed by a short one. The
+// number of lines is not increased.
+func balance(c Cue, max int) Cue {
+	if len(c.Lines) < 2 {
+		return c
+	}
+	words := strings.Fields(strings.Join(c.Lines, " "))
+	total := utf8.RuneCountInString(strings.Join(words, " "))
+
+	for width := (total + len(c.Lines) - 1) / len(c.Lines); width <= max; width++ {
+		if lines := wrap(words, width); len(lines) <= len(c.Lines) {
+			c.Lines = lines
+			return c
+		}
+	}
+	return c
+}
+
+// wrap wraps the words into lines of at most the given width. Words longer than
+// the width are placed on a line of their own.
+func wrap(words []string, width int) []string {
+	var ret []string
+	for _, w := range words {
+		if n := len(ret); n > 0 && utf8.RuneCountInString(ret[n-1])+1+utf8.RuneCountInString(w) <= width {
+			ret[n-1] += " " + w
+			continue
+		}
+		ret = append(ret, w)
+	}
+	return ret
+}
+
+// WriteSRT writes the cues in SubRip (SRT) format.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	for i, c := range cues {
+		if _, err := fmt.Fprintf(w, "%v\n%v --> %v\n%v\n\n", i+1, formatCueTime(c.Start, ","), formatCueTime(c.End, ","), strings.Join(c.Lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteVTT writes the cues in WebVTT format. If voices, cues of known speakers
+// are tagged as spoken by them, such as "<v Speaker 1>".
+func WriteVTT(w io.Writer, cues []Cue, voices bool) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		lines := make([]string, len(c.Lines))
+		for i, l := range c.Lines {
+			lines[i] = vttEscaper.Replace(l)
+		}
+		if voices && c.Speaker != 0 {
+			lines[0] = fmt.Sprintf("<v Speaker %v>%v", c.Speaker, lines[0])
+		}
+		if _, err := fmt.Fprintf(w, "%v --> %v\n%v\n\n", formatCueTime(c.Start, "."), formatCueTime(c.End, "."), strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// WriteLRC writes the cues as line-timed LRC lyrics with the given title, such
+// as for karaoke-style display. Each cue becomes a single line.
+func WriteLRC(w io.Writer, title string, cues []Cue) error {
+	if _, err := fmt.Fprintf(w, "[ti:%v]\n", title); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		cs := c.Start.Milliseconds() / 10
+		if _, err := fmt.Fprintf(w, "[%02d:%02d.%02d]%v\n", cs/6000, cs/100%60, cs%100, strings.Join(c.Lines, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatCueTime formats the offset as "hh:mm:ss.mmm" with the given separator
+// before the milliseconds.
+func formatCueTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%v%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,149 @@
+package transcribe
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCues(t *testing.T) {
+	tests := []struct {
+		name     string
+		phrases  []Phrase
+		opts     SubtitleOptions
+		expected []Cue
+	}{
+		{
+			"single",
+			[]Phrase{{Words: timed(0, 0, "hello", "world")}},
+			SubtitleOptions{},
+			[]Cue{{Start: 0, End: 2 * time.Second, Lines: []string{"hello world"}}},
+		},
+		{
+			"line length",
+			[]Phrase{{Words: timed(0, 0, "aaaa", "bbbb", "cccc", "dddd", "eeee")}},
+			SubtitleOptions{MaxLineChars: 9, MaxLines: 2},
+			[]Cue{
+				{Start: 0, End: 4 * time.Second, Lines: []string{"aaaa bbbb", "cccc dddd"}},
+				{Start: 4 * time.Second, End: 5 * time.Second, Lines: []string{"eeee"}},
+			},
+		},
+		{
+			"duration",
+			[]Phrase{{Words: timed(0, 0, "a", "b", "c", "d")}},
+			SubtitleOptions{MaxDuration: 2 * time.Second},
+			[]Cue{
+				{Start: 0, End: 2 * time.Second, Lines: []string{"a b"}},
+				{Start: 2 * time.Second, End: 4 * time.Second, Lines: []string{"c d"}},
+			},
+		},
+		{
+			"speakers",
+			[]Phrase{{Words: append(timed(0, 1, "a", "b"), timed(2*time.Second, 2, "c")...)}},
+			SubtitleOptions{},
+			[]Cue{
+				{Start: 0, End: 2 * time.Second, Lines: []string{"a b"}, Speaker: 1},
+				{Start: 2 * time.Second, End: 3 * time.Second, Lines: []string{"c"}, Speaker: 2},
+			},
+		},
+		{
+			"phrases",
+			[]Phrase{{Words: timed(0, 0, "a")}, {Text: "untimed"}, {Words: timed(5*time.Second, 0, "b")}},
+			SubtitleOptions{},
+			[]Cue{
+				{Start: 0, End: time.Second, Lines: []string{"a"}},
+				{Start: 5 * time.Second, End: 6 * time.Second, Lines: []string{"b"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := Cues(tt.phrases, tt.opts); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Cues(%v) = %+v, want %+v", tt.name, actual, tt.expected)
+		}
+	}
+}
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		max      int
+		expected []string
+	}{
+		{[]string{"one"}, 42, []string{"one"}},
+		{[]string{"the quick brown fox jumps", "over"}, 25, []string{"the quick brown", "fox jumps over"}},
+		{[]string{"aaaa bbbb cccc", "dddd"}, 14, []string{"aaaa bbbb", "cccc dddd"}},
+		{[]string{"aaaaaaaaaa", "b"}, 10, []string{"aaaaaaaaaa", "b"}},
+		{[]string{"a b c d e f", "g"}, 11, []string{"a b c d", "e f g"}},
+		{[]string{"a b c d e f g", "h", "i"}, 13, []string{"a b c", "d e f", "g h i"}},
+	}
+
+	for _, tt := range tests {
+		actual := balance(Cue{Lines: append([]string{}, tt.lines...)}, tt.max)
+		if !reflect.DeepEqual(actual.Lines, tt.expected) {
+			t.Errorf("balance(%q, %v) = %q, want %q", tt.lines, tt.max, actual.Lines, tt.expected)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		{"", 10, nil},
+		{"a b c", 10, []string{"a b c"}},
+		{"a b c", 3, []string{"a b", "c"}},
+		{"verylongword a", 4, []string{"verylongword", "a"}},
+		{"ää öö", 5, []string{"ää öö"}},
+	}
+
+	for _, tt := range tests {
+		if actual := wrap(strings.Fields(tt.text), tt.width); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("wrap(%q, %v) = %q, want %q", tt.text, tt.width, actual, tt.expected)
+		}
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	cues := []Cue{
+		{Start: 1500 * time.Millisecond, End: 3723004 * time.Millisecond, Lines: []string{"a <b>", "c"}, Speaker: 2},
+	}
+
+	var srt, vtt, lrc bytes.Buffer
+	if err := WriteSRT(&srt, cues); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteVTT(&vtt, cues, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLRC(&lrc, "title", cues); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, actual, expected string
+	}{
+		{"srt", srt.String(), "1\n00:00:01,500 --> 01:02:03,004\na <b>\nc\n\n"},
+		{"vtt", vtt.String(), "WEBVTT\n\n00:00:01.500 --> 01:02:03.004\n<v Speaker 2>a &lt;b&gt;\nc\n\n"},
+		{"lrc", lrc.String(), "[ti:title]\n[00:01.50]a <b> c\n"},
+	}
+	for _, tt := range tests {
+		if tt.actual != tt.expected {
+			t.Errorf("Write(%v) = %q, want %q", tt.name, tt.actual, tt.expected)
+		}
+	}
+}
+
+// timed returns words of one second each from the given start.
+func timed(start time.Duration, speaker int, words ...string) []Word {
+	var ret []Word
+	for i, w := range words {
+		s := start + time.Duration(i)*time.Second
+		ret = append(ret, Word{Text: w, Start: s, End: s + time.Second, Speaker: speaker})
+	}
+	return ret
+}
@@ -4,53 +4,470 @@ package transcribe
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"sort"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/speech/apiv1"
+	"github.com/herohde/transcribe/pkg/util/retryx"
 	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
-// Submit transcribes an 44.1kHz wav file (uploaded to GCS) via the Google Speech
-// API. The call is blocking. It returns a list of phrases.
-func Submit(ctx context.Context, cl *speech.Client, bucket, object string) ([]string, error) {
+// Phrase is a transcribed segment of audio.
+type Phrase struct {
+	// Text is the transcript of the phrase.
+	Text string
+	// Confidence is the estimated confidence in [0;1]. Zero if not provided.
+	Confidence float32
+	// Words are the individual words of the phrase, if provided.
+	Words []Word
+	// Channel is the audio channel of the phrase, if channels are recognized
+	// separately. Zero otherwise.
+	Channel int
+}
+
+// Word is a single transcribed word.
+type Word struct {
+	// Text is the transcript of the word.
+	Text string
+	// Start and End are the offsets of the word in the audio, if provided.
+	Start, End time.Duration
+	// Confidence is the estimated confidence in [0;1]. Zero if not provided.
+	Confidence float32
+	// Speaker is the speaker tag, if diarization is enabled. Zero otherwise.
+	Speaker int
+}
+
+// MinSampleRate and MaxSampleRate are the supported sample rates in Hz. The
+// API recommends 16kHz or higher.
+const (
+	MinSampleRate = 8000
+	MaxSampleRate = 48000
+)
+
+// DefaultPollInterval is the default interval for polling operation progress.
+const DefaultPollInterval = 10 * time.Second
+
+// Progress is the reported progress of an operation.
+type Progress struct {
+	// Percent is the estimated progress in [0;100].
+	Percent int
+	// Start is the time the operation started.
+	Start time.Time
+	// LastUpdate is the time of the most recent progress update.
+	LastUpdate time.Time
+}
+
+// Encodings of audio.
+const (
+	LINEAR16 = speechpb.RecognitionConfig_LINEAR16
+	FLAC     = speechpb.RecognitionConfig_FLAC
+	MULAW    = speechpb.RecognitionConfig_MULAW
+)
+
+// PhoneCallModel is the model for telephony audio, typically 8kHz.
+const PhoneCallModel = "phone_call"
+
+// Options hold optional settings for transcription.
+type Options struct {
+	// Encoding is the encoding of the audio. If unspecified, LINEAR16 (16-bit
+	// PCM wav) is assumed.
+	Encoding speechpb.RecognitionConfig_AudioEncoding
+	// SampleRate is the sample rate of the audio in Hz. If zero, it is read
+	// from the file header by the API.
+	SampleRate int
+	// Channels is the number of channels of the audio. If zero, mono is assumed.
+	Channels int
+	// Separate indicates that the channels are recognized separately, such as
+	// for call recordings with a channel per party. Phrases have the channel
+	// set and are ordered by time.
+	Separate bool
+	// Model is the recognition model, such as PhoneCallModel. If empty, the
+	// model of the base config or the default model is used.
+	Model string
+	// Config is an optional base recognition config for fields not otherwise
+	// exposed. Other options and required settings take precedence.
+	Config *speechpb.RecognitionConfig
+	// Progress, if set, is called with the progress of the operation whenever
+	// polled.
+	Progress func(p Progress)
+	// PollInterval is the interval for polling the operation. If zero, the
+	// DefaultPollInterval is used.
+	PollInterval time.Duration
+	// Retry is the retry policy for transient API errors. The zero value does
+	// not retry.
+	Retry retryx.Policy
+	// Raw, if set, is called with the raw, unmodified API response, such as for
+	// archiving.
+	Raw func(resp proto.Message)
+}
+
+// Submit transcribes 16-bit PCM wav or flac audio via the Google Speech API
+// using a long-running operation. The call is blocking. It returns a list of
+// phrases. Errors are of type *Error and can be matched against
+// ErrQuotaExceeded, etc.
+func Submit(ctx context.Context, cl *speech.Client, src Source, opts Options) ([]Phrase, error) {
+	audio, err := src.audio()
+	if err != nil {
+		return nil, err
+	}
 	req := &speechpb.LongRunningRecognizeRequest{
-		Config: &speechpb.RecognitionConfig{
-			Encoding:        speechpb.RecognitionConfig_LINEAR16,
-			SampleRateHertz: 44100,
-			LanguageCode:    "en-US",
-		},
-		Audio: &speechpb.RecognitionAudio{
-			AudioSource: &speechpb.RecognitionAudio_Uri{Uri: fmt.Sprintf("gs://%v/%v", bucket, object)},
-		},
+		Config: newConfig(opts),
+		Audio:  audio,
 	}
 
-	op, err := cl.LongRunningRecognize(ctx, req)
+	var op *speech.LongRunningRecognizeOperation
+	err = retryx.Do(ctx, opts.Retry, func() error {
+		var err error
+		op, err = cl.LongRunningRecognize(ctx, req)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+		return nil, newError("request", err)
 	}
-	resp, err := op.Wait(ctx)
+	resp, err := wait(ctx, op, opts)
 	if err != nil {
-		return nil, fmt.Errorf("transcribe failed: %v", err)
+		return nil, newError("transcribe", err)
+	}
+	if opts.Raw != nil {
+		opts.Raw(resp)
 	}
+	return toPhrases(resp.Results), nil
+}
+
+// Recognize transcribes short (< 1 min) 16-bit PCM wav or flac audio via the
+// Google Speech API synchronously. It is intended for small inline clips.
+// Progress is not reported.
+func Recognize(ctx context.Context, cl *speech.Client, src Source, opts Options) ([]Phrase, error) {
+	audio, err := src.audio()
+	if err != nil {
+		return nil, err
+	}
+	req := &speechpb.RecognizeRequest{
+		Config: newConfig(opts),
+		Audio:  audio,
+	}
+
+	var resp *speechpb.RecognizeResponse
+	err = retryx.Do(ctx, opts.Retry, func() error {
+		var err error
+		resp, err = cl.Recognize(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, newError("recognize", err)
+	}
+	if opts.Raw != nil {
+		opts.Raw(resp)
+	}
+	return toPhrases(resp.Results), nil
+}
+
+func toPhrases(results []*speechpb.SpeechRecognitionResult) []Phrase {
+	// If diarized, the final result of each channel repeats all words of the
+	// channel with speaker tags. The earlier results are kept for their text,
+	// but take their words from the final result, so words are not duplicated.
+
+	final := map[int]int{} // channel -> index of final result
+	for i, result := range results {
+		final[int(result.ChannelTag)] = i
+	}
+	tagged := map[int][]Word{} // channel -> words of diarized final result
+
+	var phrases []Phrase
+	separate := false
+	for i, result := range results {
+		separate = separate || result.ChannelTag > 1
+		channel := int(result.ChannelTag)
 
-	var phrases []string
-	for _, result := range resp.Results {
 		// We submit requests which return exactly 1 alternative for each
 		// phrase. So we don't have to handle "alternatives" in any real sense.
 		for _, alt := range result.Alternatives {
-			// TODO(herohde) 6/16//2017: Add extra text, if low confidence?
-			phrases = append(phrases, alt.Transcript)
+			phrase := Phrase{Text: alt.Transcript, Confidence: alt.Confidence, Channel: channel}
+			diarized := false
+			for _, w := range alt.Words {
+				phrase.Words = append(phrase.Words, Word{
+					Text:       w.Word,
+					Start:      w.StartTime.AsDuration(),
+					End:        w.EndTime.AsDuration(),
+					Confidence: w.Confidence,
+					Speaker:    int(w.SpeakerTag),
+				})
+				diarized = diarized || w.SpeakerTag != 0
+			}
+			if diarized && final[channel] == i && hasEarlier(results, i) {
+				tagged[channel] = phrase.Words
+				continue
+			}
+			phrases = append(phrases, phrase)
+		}
+	}
+	for channel, words := range tagged {
+		assignWords(phrases, channel, words)
+	}
+
+	// Results of separately recognized channels may be grouped by channel.
+	// Order them by time, so that the channels interleave as spoken.
+	if separate {
+		sort.SliceStable(phrases, func(i, j int) bool {
+			return start(phrases[i]) < start(phrases[j])
+		})
+	}
+	return phrases
+}
+
+// hasEarlier returns true iff a result before the given one is of the same
+// channel.
+func hasEarlier(results []*speechpb.SpeechRecognitionResult, i int) bool {
+	for _, r := range results[:i] {
+		if r.ChannelTag == results[i].ChannelTag {
+			return true
+		}
+	}
+	return false
+}
+
+// assignWords replaces the words of the phrases of the given channel with the
+// given words, ordered by time. Each word is assigned to the last phrase with
+// words starting at or before it.
+func assignWords(phrases []Phrase, channel int, words []Word) {
+	var targets []int
+	var starts []time.Duration
+	for i, p := range phrases {
+		if p.Channel == channel && len(p.Words) > 0 {
+			targets = append(targets, i)
+			starts = append(starts, start(p))
+			phrases[i].Words = nil
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	j := 0
+	for _, w := range words {
+		for j+1 < len(targets) && starts[j+1] <= w.Start {
+			j++
+		}
+		phrases[targets[j]].Words = append(phrases[targets[j]].Words, w)
+	}
+}
+
+// Decode returns the phrases of the given response, such as one archived via
+// Options.Raw.
+func Decode(resp *speechpb.LongRunningRecognizeResponse) []Phrase {
+	return toPhrases(resp.Results)
+}
+
+// Settings returns a deterministic encoding of the recognition config for the
+// given options, such as to key cached responses.
+func Settings(opts Options) string {
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(newConfig(opts))
+	return string(data)
+}
+
+// Remap returns the phrases with word offsets mapped by the given function,
+// such as when silence was removed from the audio before transcription.
+func Remap(phrases []Phrase, fn func(time.Duration) time.Duration) []Phrase {
+	var ret []Phrase
+	for _, p := range phrases {
+		words := make([]Word, len(p.Words))
+		for i, w := range p.Words {
+			w.Start, w.End = fn(w.Start), fn(w.End)
+			words[i] = w
+		}
+		if len(words) > 0 {
+			p.Words = words
 		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// newConfig returns the recognition config for the given options. Explicit
+// options take precedence over the base config, if any.
+func newConfig(opts Options) *speechpb.RecognitionConfig {
+	config := &speechpb.RecognitionConfig{}
+	if opts.Config != nil {
+		config = proto.Clone(opts.Config).(*speechpb.RecognitionConfig)
+	}
+
+	config.Encoding = LINEAR16
+	if opts.Encoding != speechpb.RecognitionConfig_ENCODING_UNSPECIFIED {
+		config.Encoding = opts.Encoding
+	}
+	if opts.SampleRate > 0 {
+		config.SampleRateHertz = int32(opts.SampleRate)
+	}
+	if opts.Channels > 0 {
+		config.AudioChannelCount = int32(opts.Channels)
+	}
+	if opts.Separate {
+		config.EnableSeparateRecognitionPerChannel = true
+	}
+	if opts.Model != "" {
+		config.Model = opts.Model
 	}
-	return phrases, nil
+	if config.LanguageCode == "" {
+		config.LanguageCode = "en-US"
+	}
+	config.EnableWordConfidence = true
+	config.EnableWordTimeOffsets = true
+	return config
+}
+
+// LoadConfig reads a v1 RecognitionConfig in JSON format from the given file,
+// such as:
+//
+//	{"languageCode": "de-DE", "model": "video", "useEnhanced": true}
+//
+// It can be used as a base config for fields not otherwise exposed.
+func LoadConfig(filename string) (*speechpb.RecognitionConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config := &speechpb.RecognitionConfig{}
+	if err := protojson.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid recognition config %v: %v", filename, err)
+	}
+	return config, nil
+}
+
+// wait polls the operation until done and reports progress, if requested.
+func wait(ctx context.Context, op *speech.LongRunningRecognizeOperation, opts Options) (*speechpb.LongRunningRecognizeResponse, error) {
+	if opts.Progress == nil {
+		var resp *speechpb.LongRunningRecognizeResponse
+		err := retryx.Do(ctx, opts.Retry, func() error {
+			var err error
+			resp, err = op.Wait(ctx)
+			return err
+		})
+		return resp, err
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		var resp *speechpb.LongRunningRecognizeResponse
+		err := retryx.Do(ctx, opts.Retry, func() error {
+			var err error
+			resp, err = op.Poll(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if op.Done() {
+			return resp, nil
+		}
+		if md, err := op.Metadata(); err == nil && md != nil {
+			opts.Progress(Progress{
+				Percent:    int(md.ProgressPercent),
+				Start:      md.StartTime.AsTime(),
+				LastUpdate: md.LastUpdateTime.AsTime(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// MarkLowConfidence rewrites the text of phrases with words below the given
+// confidence threshold, such as "[low: word]", so that editors know where to
+// listen again. Consecutive low-confidence words are marked together. If no
+// word-level confidence is present, the whole phrase is marked instead.
+func MarkLowConfidence(phrases []Phrase, threshold float32) []Phrase {
+	var ret []Phrase
+	for _, p := range phrases {
+		if len(p.Words) == 0 {
+			if p.Confidence > 0 && p.Confidence < threshold {
+				p.Text = markLow(strings.TrimSpace(p.Text))
+			}
+			ret = append(ret, p)
+			continue
+		}
+
+		var words, low []string
+		for _, w := range p.Words {
+			if w.Confidence > 0 && w.Confidence < threshold {
+				low = append(low, w.Text)
+				continue
+			}
+			if len(low) > 0 {
+				words = append(words, markLow(strings.Join(low, " ")))
+				low = nil
+			}
+			words = append(words, w.Text)
+		}
+		if len(low) > 0 {
+			words = append(words, markLow(strings.Join(low, " ")))
+		}
+		p.Text = strings.Join(words, " ")
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func markLow(text string) string {
+	return fmt.Sprintf("[low: %v]", text)
+}
+
+// AnnotateConfidence appends the confidence to the text of each phrase, such as
+// "(0.72)", so that reviewers can prioritize low-confidence sections. If the
+// phrase confidence is not provided, the mean word confidence is used. Phrases
+// without any confidence are not annotated.
+func AnnotateConfidence(phrases []Phrase) []Phrase {
+	var ret []Phrase
+	for _, p := range phrases {
+		if c := confidence(p); c > 0 {
+			p.Text = fmt.Sprintf("%v (%.2f)", strings.TrimSpace(p.Text), c)
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// confidence returns the confidence of the phrase or, if not provided, the mean
+// confidence of its words. Zero if unknown.
+func confidence(p Phrase) float32 {
+	if p.Confidence > 0 {
+		return p.Confidence
+	}
+	var sum float32
+	n := 0
+	for _, w := range p.Words {
+		if w.Confidence > 0 {
+			sum += w.Confidence
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float32(n)
 }
 
 // PostProcess cleans up the phrases and concatenates them to a single text.
 // For now, such post-processing is trivial.
-func PostProcess(phrases []string) string {
+func PostProcess(phrases []Phrase) string {
+	var texts []string
+	for _, p := range phrases {
+		texts = append(texts, p.Text)
+	}
 
 	// TODO(herohde) 6/11/2017: Add configurable post-processing.
-	data := strings.Join(phrases, " ")
+	data := strings.Join(texts, " ")
 
 	data = strings.Replace(data, "  ", " ", -1)
 	data = strings.Replace(data, "\n ", "\n", -1)
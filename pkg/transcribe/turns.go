@@ -0,0 +1,134 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Turns regroups the phrases into approximate speaker turns, one phrase per
+// turn, for providers or recordings without diarization. A new turn starts at
+// pauses between words of at least the given duration, at speaker changes, if
+// diarized, and at channel switches, if channels are recognized separately.
+// Phrases without word-level information form turns of their own.
+func Turns(phrases []Phrase, pause time.Duration) []Phrase {
+	var ret []Phrase
+	var last Word
+	var speaker int // last speaker of the current turn, if known
+	for _, p := range phrases {
+		if len(p.Words) == 0 {
+			if n := len(ret); n > 0 && len(ret[n-1].Words) == 0 && ret[n-1].Channel == p.Channel {
+				ret[n-1].Text = strings.TrimSpace(ret[n-1].Text) + " " + strings.TrimSpace(p.Text)
+				continue
+			}
+			ret = append(ret, Phrase{Text: strings.TrimSpace(p.Text), Channel: p.Channel})
+			continue
+		}
+
+		for _, w := range p.Words {
+			n := len(ret)
+			if n == 0 || len(ret[n-1].Words) == 0 || isTurn(ret[n-1], last, speaker, w, p.Channel, pause) {
+				ret = append(ret, Phrase{Channel: p.Channel})
+				speaker = 0
+				n++
+			}
+			ret[n-1].Words = append(ret[n-1].Words, w)
+			last = w
+			if w.Speaker != 0 {
+				speaker = w.Speaker
+			}
+		}
+	}
+
+	for i, t := range ret {
+		if len(t.Words) == 0 {
+			continue
+		}
+		texts := make([]string, len(t.Words))
+		for j, w := range t.Words {
+			texts[j] = w.Text
+		}
+		ret[i].Text = strings.Join(texts, " ")
+	}
+	return ret
+}
+
+// isTurn returns true iff the word starts a new turn after the given turn, its
+// last word and last speaker, if known. Words without speaker tag do not end
+// the turn of the last speaker.
+func isTurn(turn Phrase, last Word, speaker int, w Word, channel int, pause time.Duration) bool {
+	switch {
+	case turn.Channel != channel:
+		return true
+	case speaker != 0 && w.Speaker != 0 && speaker != w.Speaker:
+		return true
+	default:
+		return pause > 0 && w.Start-last.End >= pause
+	}
+}
+
+// FormatTurns formats the turns as text with a paragraph per turn, prefixed by
+// a "[hh:mm:ss]" timestamp and the speaker or channel, if known.
+func FormatTurns(turns []Phrase) string {
+	var sb strings.Builder
+	for i, t := range turns {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		if len(t.Words) > 0 {
+			fmt.Fprintf(&sb, "[%v] ", FormatTimestamp(start(t)))
+		}
+		switch {
+		case len(t.Words) > 0 && t.Words[0].Speaker != 0:
+			fmt.Fprintf(&sb, "Speaker %v: ", t.Words[0].Speaker)
+		case t.Channel != 0:
+			fmt.Fprintf(&sb, "Channel %v: ", t.Channel)
+		}
+		sb.WriteString(strings.TrimSpace(t.Text))
+	}
+	return sb.String()
+}
+
+// FormatDialogue formats the turns as dialogue with a paragraph per turn,
+// prefixed by the speaker, such as "S1: ...", if known. Without speakers, the
+// turns are plain paragraphs.
+func FormatDialogue(turns []Phrase) string {
+	var sb strings.Builder
+	for i, t := range turns {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		if len(t.Words) > 0 && t.Words[0].Speaker != 0 {
+			fmt.Fprintf(&sb, "S%v: ", t.Words[0].Speaker)
+		}
+		sb.WriteString(strings.TrimSpace(t.Text))
+	}
+	return sb.String()
+}
+
+// Diarized returns true iff any word has a speaker tag.
+func Diarized(phrases []Phrase) bool {
+	for _, p := range phrases {
+		for _, w := range p.Words {
+			if w.Speaker != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BySpeaker returns the phrases of each speaker, if diarization is enabled, such
+// as to extract the words of a single interviewee. Phrases are split at speaker
+// changes. Words without speaker tag are omitted.
+func BySpeaker(phrases []Phrase) map[int][]Phrase {
+	ret := map[int][]Phrase{}
+	for _, p := range phrases {
+		for _, sp := range splitSpeakers(p) {
+			if len(sp.Words) > 0 && sp.Words[0].Speaker != 0 {
+				ret[sp.Words[0].Speaker] = append(ret[sp.Words[0].Speaker], sp)
+			}
+		}
+	}
+	return ret
+}
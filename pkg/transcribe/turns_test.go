@@ -0,0 +1,107 @@
+package transcribe
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTurns(t *testing.T) {
+	tests := []struct {
+		name     string
+		phrases  []Phrase
+		pause    time.Duration
+		expected []string // "channel:text" of each turn
+	}{
+		{
+			"pause",
+			[]Phrase{{Words: []Word{word("a", 0, 1, 0), word("b", 1, 2, 0), word("c", 5, 6, 0)}}},
+			2 * time.Second,
+			[]string{"0:a b", "0:c"},
+		},
+		{
+			"no pause",
+			[]Phrase{{Words: []Word{word("a", 0, 1, 0), word("b", 5, 6, 0)}}},
+			0,
+			[]string{"0:a b"},
+		},
+		{
+			"across phrases",
+			[]Phrase{{Words: []Word{word("a", 0, 1, 0)}}, {Words: []Word{word("b", 1, 2, 0)}}},
+			2 * time.Second,
+			[]string{"0:a b"},
+		},
+		{
+			"speakers",
+			[]Phrase{{Words: []Word{word("a", 0, 1, 1), word("b", 1, 2, 0), word("c", 2, 3, 1), word("d", 3, 4, 2)}}},
+			0,
+			[]string{"0:a b c", "0:d"},
+		},
+		{
+			"channels",
+			[]Phrase{{Words: []Word{word("a", 0, 1, 0)}, Channel: 1}, {Words: []Word{word("b", 1, 2, 0)}, Channel: 2}, {Words: []Word{word("c", 2, 3, 0)}, Channel: 2}},
+			0,
+			[]string{"1:a", "2:b c"},
+		},
+		{
+			"untimed",
+			[]Phrase{{Text: " a "}, {Text: "b"}, {Words: []Word{word("c", 0, 1, 0)}}, {Text: "d"}},
+			time.Second,
+			[]string{"0:a b", "0:c", "0:d"},
+		},
+	}
+
+	for _, tt := range tests {
+		var actual []string
+		for _, turn := range Turns(tt.phrases, tt.pause) {
+			actual = append(actual, fmt.Sprintf("%v:%v", turn.Channel, turn.Text))
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Turns(%v) = %q, want %q", tt.name, actual, tt.expected)
+		}
+	}
+}
+
+func TestFormatTurns(t *testing.T) {
+	turns := []Phrase{
+		{Text: "hello there", Words: []Word{word("hello", 61, 62, 1), word("there", 62, 63, 1)}},
+		{Text: "hi", Words: []Word{word("hi", 64, 65, 0)}, Channel: 2},
+		{Text: " untimed "},
+	}
+
+	tests := []struct {
+		name, actual, expected string
+	}{
+		{"turns", FormatTurns(turns), "[00:01:01] Speaker 1: hello there\n\n[00:01:04] Channel 2: hi\n\nuntimed"},
+		{"dialogue", FormatDialogue(turns), "S1: hello there\n\nhi\n\nuntimed"},
+	}
+	for _, tt := range tests {
+		if tt.actual != tt.expected {
+			t.Errorf("Format(%v) = %q, want %q", tt.name, tt.actual, tt.expected)
+		}
+	}
+}
+
+func TestBySpeaker(t *testing.T) {
+	phrases := []Phrase{{Words: []Word{word("a", 0, 1, 1), word("b", 1, 2, 2), word("c", 2, 3, 1)}}}
+
+	actual := map[int][]string{}
+	for speaker, list := range BySpeaker(phrases) {
+		for _, p := range list {
+			actual[speaker] = append(actual[speaker], p.Text)
+		}
+	}
+	expected := map[int][]string{1: {"a", "c"}, 2: {"b"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("BySpeaker = %q, want %q", actual, expected)
+	}
+	if !Diarized(phrases) || Diarized([]Phrase{{Words: []Word{word("a", 0, 1, 0)}}}) {
+		t.Errorf("Diarized is wrong")
+	}
+}
+
+// word returns a word with the given offsets in seconds and speaker.
+func word(text string, start, end, speaker int) Word {
+	return Word{Text: text, Start: time.Duration(start) * time.Second, End: time.Duration(end) * time.Second, Speaker: speaker}
+}
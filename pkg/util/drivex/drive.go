@@ -0,0 +1,111 @@
+// Package drivex contains utilities for Google Drive.
+package drivex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// DocMimeType is the mime type of Google Docs.
+const DocMimeType = "application/vnd.google-apps.document"
+
+// NewClient returns a new Drive client with Full scope. If a credentials file,
+// such as a service account key, is given it is used. Otherwise, Application
+// Default Credentials are used.
+func NewClient(ctx context.Context, credentials string) (*drive.Service, error) {
+	if credentials == "" {
+		httpClient, err := google.DefaultClient(ctx, drive.DriveScope)
+		if err != nil {
+			return nil, err
+		}
+		return drive.New(httpClient)
+	}
+
+	data, err := ioutil.ReadFile(credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %v", err)
+	}
+	creds, err := google.CredentialsFromJSON(ctx, data, drive.DriveScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials: %v", err)
+	}
+	return drive.New(oauth2.NewClient(ctx, creds.TokenSource))
+}
+
+// List returns the (non-trashed) files in the given folder.
+func List(ctx context.Context, cl *drive.Service, folder string) ([]*drive.File, error) {
+	var ret []*drive.File
+
+	q := fmt.Sprintf("'%v' in parents and trashed = false", quote(folder))
+	call := cl.Files.List().Q(q).Fields("nextPageToken, files(id, name, mimeType, size, videoMediaMetadata(durationMillis))").SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	err := call.Pages(ctx, func(list *drive.FileList) error {
+		ret = append(ret, list.Files...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder %v: %v", folder, err)
+	}
+	return ret, nil
+}
+
+// quote escapes the string for use in a single-quoted query literal.
+func quote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// Download downloads the given file to the local filename.
+func Download(ctx context.Context, cl *drive.Service, id, filename string) error {
+	resp, err := cl.Files.Get(id).SupportsAllDrives(true).Context(ctx).Download()
+	if err != nil {
+		return fmt.Errorf("failed to download %v: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, resp.Body); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to download %v: %v", id, err)
+	}
+	return fd.Close()
+}
+
+// ReadHead returns the first n bytes of the given file, such as to read the
+// header of an audio file without downloading it.
+func ReadHead(ctx context.Context, cl *drive.Service, id string, n int64) ([]byte, error) {
+	call := cl.Files.Get(id).SupportsAllDrives(true).Context(ctx)
+	call.Header().Set("Range", fmt.Sprintf("bytes=0-%v", n-1))
+	resp, err := call.Download()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, n))
+}
+
+// UploadText uploads the given text as a new file in the given folder. If doc
+// is true, the text is converted to a Google Doc.
+func UploadText(ctx context.Context, cl *drive.Service, folder, name string, data []byte, doc bool) error {
+	f := &drive.File{Name: name, Parents: []string{folder}}
+	if doc {
+		f.MimeType = DocMimeType
+	}
+	_, err := cl.Files.Create(f).Media(bytes.NewReader(data), googleapi.ContentType("text/plain")).SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to upload %v: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package filex contains utilities for writing files safely on shared network
+// volumes, such as NFS or SMB shares written to by parallel workers.
+package filex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// staleLock is the age after which a lock is considered abandoned, such as
+	// by a crashed worker, and broken.
+	staleLock = 10 * time.Minute
+	// lockTimeout is the maximum time to wait for a lock.
+	lockTimeout = time.Minute
+	lockPoll    = 100 * time.Millisecond
+)
+
+// Lock acquires an advisory lock on the given file by exclusively creating a
+// "<file>.lock" file next to it, which works on network file systems where
+// flock does not. It returns a function to release the lock.
+func Lock(filename string) (func(), error) {
+	name := filename + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		fd, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintln(fd, owner())
+			fd.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %v: %v", filename, err)
+		}
+
+		if breakStale(name) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock %v: timeout waiting for %v", filename, name)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// breakStale removes the given lock file, if stale, and returns true if so.
+// Breaking is itself guarded by a lock, so that concurrent workers do not
+// remove a lock that another worker acquired after breaking the stale one.
+func breakStale(name string) bool {
+	if !isStale(name) {
+		return false
+	}
+
+	guard := name + ".break"
+	fd, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if isStale(guard) {
+			os.Remove(guard) // abandoned while breaking
+		}
+		return false
+	}
+	fd.Close()
+	defer os.Remove(guard)
+
+	if !isStale(name) {
+		return false // broken and re-acquired by another worker
+	}
+	return os.Remove(name) == nil
+}
+
+func isStale(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && time.Since(fi.ModTime()) > staleLock
+}
+
+// WriteFile writes the data to the given file under an advisory lock. If
+// atomic, the data is written to a uniquely named temporary file in the same
+// directory, which is then renamed, so that readers never see a partial file.
+// Otherwise, for file systems without atomic rename, the file is written in
+// place.
+func WriteFile(filename string, data []byte, perm os.FileMode, atomic bool) error {
+	unlock, err := Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !atomic {
+		if err := ioutil.WriteFile(filename, data, perm); err != nil {
+			os.Remove(filename)
+			return err
+		}
+		return nil
+	}
+
+	tmp := filepath.Join(filepath.Dir(filename), fmt.Sprintf(".%v.%v.tmp", filepath.Base(filename), owner()))
+	fd, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename %v: %v", tmp, err)
+	}
+	return nil
+}
+
+// Partial returns true iff the given file is locked, i.e., it is being written
+// or was left partially written in place by a crashed writer.
+func Partial(filename string) bool {
+	_, err := os.Stat(filename + ".lock")
+	return err == nil
+}
+
+// tmpRE matches the temporary files of WriteFile, such as
+// ".foo.wav.txt.host-123-1a2b3c4d.tmp", with the name of the target file.
+var tmpRE = regexp.MustCompile(`^\.(.+)\.[^.]+-\d+-[0-9a-f]+\.tmp$`)
+
+// Abandoned returns the file written by WriteFile, if the given file is a lock
+// or temporary file of it older than the given age, such as left behind by a
+// crashed worker.
+func Abandoned(path string, fi os.FileInfo, age time.Duration) (string, bool) {
+	if fi.IsDir() || time.Since(fi.ModTime()) <= age {
+		return "", false
+	}
+	dir, name := filepath.Split(path)
+	for _, suffix := range []string{".lock", ".lock.break"} {
+		if target := strings.TrimSuffix(name, suffix); target != name && target != "" {
+			return filepath.Join(dir, target), true
+		}
+	}
+	if m := tmpRE.FindStringSubmatch(name); m != nil {
+		return filepath.Join(dir, m[1]), true
+	}
+	return "", false
+}
+
+// owner returns a name unique to this process and call, such as for temporary
+// files of parallel workers on different hosts.
+func owner() string {
+	host, _ := os.Hostname()
+	host = strings.ReplaceAll(host, ".", "_") // no dots, so temporary file names can be parsed
+	return fmt.Sprintf("%v-%v-%x", host, os.Getpid(), rand.Uint32())
+}
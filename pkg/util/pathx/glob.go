@@ -0,0 +1,77 @@
+package pathx
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsRecursive returns true iff the pattern contains a "**" segment, which
+// matches any number of directories, such as "recordings/**/*.wav".
+func IsRecursive(pattern string) bool {
+	for _, s := range split(pattern) {
+		if s == "**" {
+			return true
+		}
+	}
+	return false
+}
+
+// Root returns the leading directory of the pattern without glob characters,
+// such as "recordings" for "recordings/**/*.wav". It returns "." if the
+// pattern starts with a glob.
+func Root(pattern string) string {
+	segs := split(pattern)
+	var root []string
+	for _, s := range segs[:len(segs)-1] {
+		if strings.ContainsAny(s, "*?[") {
+			break
+		}
+		root = append(root, s)
+	}
+	if len(root) == 0 {
+		if filepath.IsAbs(pattern) {
+			return filepath.VolumeName(pattern) + string(filepath.Separator)
+		}
+		return "."
+	}
+	ret := strings.Join(root, string(filepath.Separator))
+	if filepath.IsAbs(pattern) && !filepath.IsAbs(ret) {
+		ret = string(filepath.Separator) + ret
+	}
+	return ret
+}
+
+// Match returns true iff the path matches the pattern. In addition to the
+// syntax of filepath.Match, a "**" segment matches any number of directories.
+func Match(pattern, path string) (bool, error) {
+	return match(split(pattern), split(path))
+}
+
+func match(pattern, path []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if ok, err := match(pattern[1:], path[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(path) == 0 {
+			return false, nil
+		}
+		if ok, err := filepath.Match(pattern[0], path[0]); !ok || err != nil {
+			return false, err
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0, nil
+}
+
+// split splits the cleaned path into its non-empty segments. Both slashes and
+// the OS separator are accepted.
+func split(path string) []string {
+	return strings.FieldsFunc(filepath.ToSlash(filepath.Clean(path)), func(r rune) bool {
+		return r == '/'
+	})
+}
@@ -0,0 +1,62 @@
+// Package pathx contains portable path utilities, notably for Windows where
+// the shell does not expand globs and paths may be long or on UNC shares.
+package pathx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Expand expands glob patterns, such as "D:\recordings\*.wav", in the given
+// arguments. Arguments without glob characters or that name existing files are
+// returned as-is. It is an error if a pattern matches no files.
+func Expand(args []string) ([]string, error) {
+	var ret []string
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			ret = append(ret, arg)
+			continue
+		}
+		if _, err := os.Stat(arg); err == nil {
+			ret = append(ret, arg) // literal filename
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %v", arg)
+		}
+		ret = append(ret, matches...)
+	}
+	return ret, nil
+}
+
+// External returns the path in a form suitable for passing to external tools,
+// such as sox or ffmpeg. On Windows, long absolute paths are given the "\\?\"
+// prefix (or "\\?\UNC\" for network shares) to lift the MAX_PATH limit. On
+// other platforms, the path is returned as-is.
+func External(path string) string {
+	return external(path)
+}
+
+// Local returns the path in a form suitable for file operations of this
+// process. On Windows, long relative paths, such as of mirrored output
+// directories, are made absolute, because the os package lifts the MAX_PATH
+// limit for absolute paths only. On other platforms, the path is returned
+// as-is.
+func Local(path string) string {
+	return local(path)
+}
+
+// SafeName returns the file name with characters that are invalid in file names
+// replaced by '_'. On Windows, these are '<>:"/\|?*' and control characters,
+// trailing dots and spaces are removed and reserved device names, such as
+// "NUL.txt", are prefixed with '_'. On other platforms, only '/' is invalid.
+func SafeName(name string) string {
+	return safeName(name)
+}
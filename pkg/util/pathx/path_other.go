@@ -0,0 +1,17 @@
+//go:build !windows
+
+package pathx
+
+import "strings"
+
+func external(path string) string {
+	return path
+}
+
+func local(path string) string {
+	return path
+}
+
+func safeName(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
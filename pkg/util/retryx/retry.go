@@ -0,0 +1,93 @@
+// Package retryx contains utilities for retrying transient failures with
+// jittered exponential backoff.
+package retryx
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy is a retry policy. The zero value does not retry.
+type Policy struct {
+	// Attempts is the maximum number of attempts, including the first.
+	Attempts int
+	// Initial is the initial backoff.
+	Initial time.Duration
+	// Max is the maximum backoff.
+	Max time.Duration
+}
+
+// DefaultPolicy is a reasonable policy for Google API calls.
+var DefaultPolicy = Policy{Attempts: 5, Initial: time.Second, Max: time.Minute}
+
+// MaxBackoff is the maximum backoff of scaled policies.
+const MaxBackoff = 10 * time.Minute
+
+// Scale returns the policy with backoffs scaled by the given factor, such as
+// in proportion to the expected duration of an operation. The factor is at
+// least 1 and the maximum backoff at most MaxBackoff.
+func (p Policy) Scale(factor float64) Policy {
+	if factor <= 1 {
+		return p
+	}
+	p.Initial = time.Duration(float64(p.Initial) * factor)
+	p.Max = time.Duration(float64(p.Max) * factor)
+	if p.Max > MaxBackoff {
+		p.Max = MaxBackoff
+	}
+	if p.Initial > p.Max {
+		p.Initial = p.Max
+	}
+	return p
+}
+
+// Do calls fn until it succeeds, fails with a non-transient error, the attempts
+// are exhausted or the context is cancelled. It returns the last error.
+func Do(ctx context.Context, p Policy, fn func() error) error {
+	backoff := p.Initial
+	for i := 1; ; i++ {
+		err := fn()
+		if err == nil || i >= p.Attempts || !IsTransient(err) {
+			return err
+		}
+
+		// Equal jitter: sleep a random duration in [backoff/2; backoff].
+
+		d := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d):
+		}
+
+		backoff *= 2
+		if p.Max > 0 && backoff > p.Max {
+			backoff = p.Max
+		}
+	}
+}
+
+// IsTransient returns true iff the error is a transient API error, such as
+// gRPC UNAVAILABLE or RESOURCE_EXHAUSTED, or HTTP 429 or 5xx.
+func IsTransient(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == http.StatusTooManyRequests || gerr.Code >= http.StatusInternalServerError
+	}
+
+	var serr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &serr) {
+		switch serr.GRPCStatus().Code() {
+		case codes.Unavailable, codes.ResourceExhausted:
+			return true
+		}
+	}
+	return false
+}
@@ -3,10 +3,16 @@ package storagex
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/seekerror/logw"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/storage/v1"
 )
 
@@ -20,38 +26,200 @@ func NewClient(ctx context.Context) (*storage.Service, error) {
 	return storage.New(httpClient)
 }
 
+// ParseURI returns the bucket and object of a GCS URI, such as
+// "gs://bucket/path/foo.wav".
+func ParseURI(uri string) (string, string, error) {
+	if !strings.HasPrefix(uri, "gs://") {
+		return "", "", fmt.Errorf("not a GCS URI: %v", uri)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(uri, "gs://"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid GCS URI: %v. Must be gs://bucket/object", uri)
+	}
+	return parts[0], parts[1], nil
+}
+
 // NewBucket creates a new GCS bucket in the given project.
-func NewBucket(cl *storage.Service, project, bucket string) error {
-	_, err := cl.Buckets.Insert(project, &storage.Bucket{Name: bucket}).Do()
+func NewBucket(ctx context.Context, cl *storage.Service, project, bucket string) error {
+	_, err := cl.Buckets.Insert(project, &storage.Bucket{Name: bucket}).Context(ctx).Do()
 	return err
 }
 
 // TryDeleteBucket tries to delete the given bucket and logs any errors.
 // Intended to deferred cleanup.
 func TryDeleteBucket(ctx context.Context, cl *storage.Service, bucket string) {
-	if err := cl.Buckets.Delete(bucket).Do(); err != nil {
+	if err := cl.Buckets.Delete(bucket).Context(ctx).Do(); err != nil {
 		logw.Errorf(ctx, "Failed to delete bucket %v: %v", bucket, err)
 	}
 }
 
-// UploadFile uploads the given file to GCS. It assumes the bucket exists.
-func UploadFile(cl *storage.Service, bucket, object, filename string) error {
+// ListBuckets returns the names of the buckets in the given project with the
+// given name prefix.
+func ListBuckets(ctx context.Context, cl *storage.Service, project, prefix string) ([]string, error) {
+	var ret []string
+	err := cl.Buckets.List(project).Prefix(prefix).Pages(ctx, func(list *storage.Buckets) error {
+		for _, b := range list.Items {
+			ret = append(ret, b.Name)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// DeleteBucket deletes the given bucket and all objects in it.
+func DeleteBucket(ctx context.Context, cl *storage.Service, bucket string) error {
+	err := cl.Objects.List(bucket).Pages(ctx, func(list *storage.Objects) error {
+		for _, o := range list.Items {
+			if err := cl.Objects.Delete(bucket, o.Name).Context(ctx).Do(); err != nil {
+				return fmt.Errorf("failed to delete object gs://%v/%v: %v", bucket, o.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return cl.Buckets.Delete(bucket).Context(ctx).Do()
+}
+
+// MissingPermissions returns the given permissions, such as
+// "storage.objects.create", that the caller lacks on the bucket.
+func MissingPermissions(ctx context.Context, cl *storage.Service, bucket string, permissions []string) ([]string, error) {
+	resp, err := cl.Buckets.TestIamPermissions(bucket, permissions).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	granted := map[string]bool{}
+	for _, p := range resp.Permissions {
+		granted[p] = true
+	}
+	var ret []string
+	for _, p := range permissions {
+		if !granted[p] {
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// Stat returns the metadata of the given object.
+func Stat(ctx context.Context, cl *storage.Service, bucket, object string) (*storage.Object, error) {
+	obj, err := cl.Objects.Get(bucket, object).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of gs://%v/%v: %w", bucket, object, err)
+	}
+	return obj, nil
+}
+
+// ContentHash returns a hash of the content of the object: the MD5 hash or,
+// for composite objects without one, the CRC32C checksum and size.
+func ContentHash(obj *storage.Object) string {
+	if obj.Md5Hash != "" {
+		return "md5:" + obj.Md5Hash
+	}
+	return fmt.Sprintf("crc32c:%v:%v", obj.Crc32c, obj.Size)
+}
+
+// ReadHead returns the first n bytes of the given object, such as to read the
+// header of an audio file without downloading it.
+func ReadHead(ctx context.Context, cl *storage.Service, bucket, object string, n int64) ([]byte, error) {
+	call := cl.Objects.Get(bucket, object).Context(ctx)
+	call.Header().Set("Range", fmt.Sprintf("bytes=0-%v", n-1))
+	resp, err := call.Download()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gs://%v/%v: %w", bucket, object, err)
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, n))
+}
+
+// DownloadFile downloads the given object to the given file.
+func DownloadFile(ctx context.Context, cl *storage.Service, bucket, object, filename string) error {
+	resp, err := cl.Objects.Get(bucket, object).Context(ctx).Download()
+	if err != nil {
+		return fmt.Errorf("failed to download gs://%v/%v: %w", bucket, object, err)
+	}
+	defer resp.Body.Close()
+
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, resp.Body); err != nil {
+		fd.Close()
+		os.Remove(filename)
+		return fmt.Errorf("failed to download gs://%v/%v: %w", bucket, object, err)
+	}
+	return fd.Close()
+}
+
+// UploadFile uploads the given file to GCS with the given custom metadata, if
+// any. The content type is derived from the file extension. It assumes the
+// bucket exists. If progress is not nil, it is called with the number of bytes
+// sent and the file size as the upload proceeds.
+func UploadFile(ctx context.Context, cl *storage.Service, bucket, object, filename string, metadata map[string]string, progress func(sent, size int64)) error {
 	fd, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer fd.Close()
 
-	if _, err := cl.Objects.Insert(bucket, &storage.Object{Name: object}).Media(fd).Do(); err != nil {
-		return fmt.Errorf("failed to create object: %v", err)
+	var r io.Reader = fd
+	if progress != nil {
+		fi, err := fd.Stat()
+		if err != nil {
+			return err
+		}
+		r = &progressReader{r: fd, size: fi.Size(), fn: progress}
+	}
+
+	obj := &storage.Object{
+		Name:        object,
+		ContentType: contentType(filename),
+		Metadata:    metadata,
+	}
+	if _, err := cl.Objects.Insert(bucket, obj).Media(r, googleapi.ContentType(obj.ContentType)).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
 	}
 	return nil
 }
 
+// progressReader is a reader that reports the number of bytes read.
+type progressReader struct {
+	r    io.Reader
+	sent int64
+	size int64
+	fn   func(sent, size int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	p.fn(p.sent, p.size)
+	return n, err
+}
+
+// contentType returns the content type of the file by extension.
+func contentType(filename string) string {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".wav":
+		return "audio/wav"
+	case ".flac":
+		return "audio/flac"
+	default:
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+		return "application/octet-stream"
+	}
+}
+
 // TryDeleteObject tries to delete the given object and logs any errors.
 // Intended to deferred cleanup.
 func TryDeleteObject(ctx context.Context, cl *storage.Service, bucket, object string) {
-	if err := cl.Objects.Delete(bucket, object).Do(); err != nil {
+	if err := cl.Objects.Delete(bucket, object).Context(ctx).Do(); err != nil {
 		logw.Errorf(ctx, "Failed to delete object gs://%v/%v: %v", bucket, object, err)
 	}
 }